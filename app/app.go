@@ -3,36 +3,98 @@ package app
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"os"
 	"time"
 
+	"github.com/Cirederf1/vehicle-server/pkg/cursor"
+	"github.com/Cirederf1/vehicle-server/pkg/pricing"
+	"github.com/Cirederf1/vehicle-server/pkg/shortcode"
 	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
 	"github.com/Cirederf1/vehicle-server/vehicle"
 	"go.uber.org/zap"
 )
 
 type App struct {
-	listener net.Listener
-	server   *http.Server
-	store    *storage.PGXStore
-	logger   *zap.Logger
+	listener         net.Listener
+	server           *http.Server
+	store            storage.Store
+	deletedRetention time.Duration
+	logger           *zap.Logger
 }
 
+// Storage backends available to the server.
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
 type Config struct {
+	// Storage is the storage backend, StoragePostgres when empty.
+	Storage       string
 	DatabaseURL   string
 	ListenAddress string
+
+	// Database connection pool settings, the pgxpool defaults apply when zero.
+	DatabaseMaxConns        int32
+	DatabaseMaxConnIdleTime time.Duration
+	DatabaseMaxConnLifetime time.Duration
+
+	// DatabaseSkipMigrations disables applying the pending migrations on startup.
+	DatabaseSkipMigrations bool
+
+	// CursorSecret signs the pagination cursors.
+	// A random one is used when empty, and cursors do not survive restarts.
+	CursorSecret string
+
+	// MaxListLimit bounds the number of vehicles listed per page, DefaultMaxListLimit when zero.
+	MaxListLimit int64
+
+	// ShortCodeAlphabet holds the characters of the generated shortcodes,
+	// shortcode.DefaultAlphabet when empty.
+	ShortCodeAlphabet string
+	// ShortCodeDenyList holds the words the generated shortcodes must not contain,
+	// shortcode.DefaultDenyList when nil. An empty, non nil, list disables it.
+	ShortCodeDenyList []string
+
+	// DeletedRetention is the duration the deleted vehicles can be restored for,
+	// they are purged afterwards. Zero keeps them forever.
+	DeletedRetention time.Duration
+
+	// ReservationHold is the duration the reservations hold their vehicle, DefaultReservationHold when zero.
+	ReservationHold time.Duration
+
+	// TariffsFile is the path of the JSON tariffs of the vehicle types, see pricing.Load.
+	// The pricing.DefaultTariffs apply in UTC when empty.
+	TariffsFile string
 }
 
-func New(ctx context.Context, cfg Config, logger *zap.Logger) (*App, error) {
+// purgeInterval is the period of the purges of the deleted vehicles.
+const purgeInterval = time.Hour
+
+// expiryInterval is the period of the expiry of the reservations,
+// the expired reservations hold their vehicle for at most this long.
+const expiryInterval = 30 * time.Second
+
+// DefaultReservationHold is the duration the reservations hold their vehicle, unless configured.
+const DefaultReservationHold = 15 * time.Minute
+
+// DefaultMaxListLimit is the maximum number of vehicles listed per page, unless configured.
+const DefaultMaxListLimit = 100
+
+func New(ctx context.Context, cfg Config, logger *zap.Logger) (_ *App, err error) {
 	logger.Info(
 		"Starting the vehicle-server",
+		zap.String("storage", cfg.Storage),
 		zap.String("database-url", cfg.DatabaseURL),
 		zap.String("listen-address", cfg.ListenAddress),
 	)
 
 	// Initializing the storage layer.
-	store, err := storage.NewPGXStore(ctx, cfg.DatabaseURL, logger)
+	store, err := newStore(ctx, cfg, logger)
 	if err != nil {
 		logger.Error(
 			"Could not create the storage",
@@ -41,6 +103,41 @@ func New(ctx context.Context, cfg Config, logger *zap.Logger) (*App, error) {
 		return nil, err
 	}
 
+	// The App closes the store once created, unless it fails to start.
+	defer func() {
+		if err != nil {
+			store.Close()
+		}
+	}()
+
+	cursors, err := newCursorCodec(cfg, logger)
+	if err != nil {
+		logger.Error(
+			"Could not create the cursor codec",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	shortCodes, err := newShortCodeGenerator(cfg)
+	if err != nil {
+		logger.Error(
+			"Could not create the shortcode generator",
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	pricer, err := newPricer(cfg)
+	if err != nil {
+		logger.Error(
+			"Could not load the tariffs",
+			zap.String("tariffs-file", cfg.TariffsFile),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
 	listener, err := net.Listen("tcp", cfg.ListenAddress)
 	if err != nil {
 		logger.Error(
@@ -50,6 +147,16 @@ func New(ctx context.Context, cfg Config, logger *zap.Logger) (*App, error) {
 		return nil, err
 	}
 
+	maxListLimit := cfg.MaxListLimit
+	if maxListLimit == 0 {
+		maxListLimit = DefaultMaxListLimit
+	}
+
+	reservationHold := cfg.ReservationHold
+	if reservationHold == 0 {
+		reservationHold = DefaultReservationHold
+	}
+
 	// Create up an http server and a router.
 	var (
 		router = http.NewServeMux()
@@ -60,21 +167,117 @@ func New(ctx context.Context, cfg Config, logger *zap.Logger) (*App, error) {
 	)
 
 	// Wire the routes.
-	router.Handle("GET /vehicles", vehicle.NewListHandler(store, logger))
-	router.Handle("POST /vehicles", vehicle.NewCreateHandler(store, logger))
+	router.Handle("GET /vehicles", vehicle.NewListHandler(store, cursors, maxListLimit, logger))
+	router.Handle("POST /vehicles", vehicle.NewCreateHandler(store, shortCodes, logger))
+	router.Handle("POST /vehicles/bulk", vehicle.NewBulkCreateHandler(store, shortCodes, logger))
+	router.Handle("GET /vehicles/{id}", vehicle.NewGetHandler(store, logger))
+	router.Handle("GET /vehicles/by-shortcode/{code}", vehicle.NewGetByShortCodeHandler(store, logger))
+	router.Handle("PATCH /vehicles/{id}", vehicle.NewUpdateHandler(store, logger))
 	router.Handle("DELETE /vehicles/{id}", vehicle.NewDeleteHandler(store, logger))
+	router.Handle("POST /vehicles/{id}/transitions", vehicle.NewTransitionHandler(store, logger))
+	router.Handle("POST /vehicles/{id}/restore", vehicle.NewRestoreHandler(store, logger))
+	// The GET routes below /vehicles/{id} need more than 3 segments,
+	// not to conflict with /vehicles/by-shortcode/{code}.
+	router.Handle("GET /vehicles/{id}/telemetry/history", vehicle.NewHistoryHandler(store, logger))
+	router.Handle("GET /vehicles/{id}/telemetry/position", vehicle.NewPositionAtHandler(store, logger))
+	router.Handle("POST /telemetry", vehicle.NewIngestHandler(store, logger))
+	router.Handle("POST /reservations", vehicle.NewReserveHandler(store, reservationHold, logger))
+	router.Handle("GET /reservations/{id}", vehicle.NewGetReservationHandler(store, logger))
+	router.Handle("POST /reservations/{id}/cancel", vehicle.NewCancelReservationHandler(store, logger))
+	router.Handle("GET /trips", vehicle.NewTripHistoryHandler(store, logger))
+	router.Handle("POST /trips", vehicle.NewStartTripHandler(store, time.Now, logger))
+	router.Handle("GET /trips/{id}", vehicle.NewGetTripHandler(store, logger))
+	router.Handle("POST /trips/{id}/end", vehicle.NewEndTripHandler(store, pricer, time.Now, logger))
+	router.Handle("GET /quotes", vehicle.NewQuoteHandler(store, pricer, time.Now, logger))
 	router.HandleFunc("GET /_/ready", func(rw http.ResponseWriter, r *http.Request) {
 		rw.WriteHeader(http.StatusOK)
 	})
 
 	return &App{
-		listener: listener,
-		server:   server,
-		store:    store,
-		logger:   logger,
+		listener:         listener,
+		server:           server,
+		store:            store,
+		deletedRetention: cfg.DeletedRetention,
+		logger:           logger,
 	}, nil
 }
 
+func newShortCodeGenerator(cfg Config) (*shortcode.Generator, error) {
+	alphabet := cfg.ShortCodeAlphabet
+	if alphabet == "" {
+		alphabet = shortcode.DefaultAlphabet
+	}
+
+	denyList := cfg.ShortCodeDenyList
+	if denyList == nil {
+		denyList = shortcode.DefaultDenyList
+	}
+
+	return shortcode.NewGenerator(alphabet, vehicle.MaxShortCodeLength, denyList)
+}
+
+// newPricer loads the tariffs, every vehicle type must have one.
+func newPricer(cfg Config) (*pricing.Pricer, error) {
+	var (
+		pricer *pricing.Pricer
+		err    error
+	)
+
+	if cfg.TariffsFile == "" {
+		pricer, err = pricing.NewPricer(pricing.DefaultTariffs, time.UTC)
+	} else {
+		var f *os.File
+		if f, err = os.Open(cfg.TariffsFile); err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		pricer, err = pricing.Load(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range vehiclestore.Types {
+		if !pricer.Has(string(t)) {
+			return nil, fmt.Errorf("missing the tariff of the %s vehicles", t)
+		}
+	}
+
+	return pricer, nil
+}
+
+func newStore(ctx context.Context, cfg Config, logger *zap.Logger) (storage.Store, error) {
+	switch cfg.Storage {
+	case StoragePostgres, "":
+		return storage.NewPGXStore(
+			ctx,
+			storage.PGXConfig{
+				DatabaseURL:     cfg.DatabaseURL,
+				MaxConns:        cfg.DatabaseMaxConns,
+				MaxConnIdleTime: cfg.DatabaseMaxConnIdleTime,
+				MaxConnLifetime: cfg.DatabaseMaxConnLifetime,
+				SkipMigrations:  cfg.DatabaseSkipMigrations,
+			},
+			logger,
+		)
+	case StorageMemory:
+		return storage.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage)
+	}
+}
+
+func newCursorCodec(cfg Config, logger *zap.Logger) (*cursor.Codec, error) {
+	if cfg.CursorSecret != "" {
+		return cursor.NewCodec([]byte(cfg.CursorSecret)), nil
+	}
+
+	logger.Warn("No cursor secret configured, pagination cursors will not survive restarts")
+
+	return cursor.NewRandomCodec()
+}
+
 func (a *App) ListenAddress() string {
 	return a.listener.Addr().String()
 }
@@ -105,6 +308,12 @@ func (a *App) Run(ctx context.Context) error {
 		}
 	}()
 
+	if a.deletedRetention > 0 {
+		go a.purgeDeleted(ctx)
+	}
+
+	go a.expireReservations(ctx)
+
 	a.logger.Info(
 		"Listening for HTTP requests",
 		zap.String("listen-address", a.listener.Addr().String()),
@@ -121,6 +330,67 @@ func (a *App) Run(ctx context.Context) error {
 	return nil
 }
 
+// purgeDeleted periodically purges the vehicles deleted for longer than the retention, until ctx is done.
+func (a *App) purgeDeleted(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := a.store.Vehicle().Purge(ctx, time.Now().Add(-a.deletedRetention))
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			a.logger.Error(
+				"Could not purge the deleted vehicles",
+				zap.Error(err),
+			)
+		case purged > 0:
+			a.logger.Info(
+				"Purged the deleted vehicles",
+				zap.Int64("count", purged),
+				zap.Duration("retention", a.deletedRetention),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireReservations periodically ends the reservations past their expiry, until ctx is done.
+func (a *App) expireReservations(ctx context.Context) {
+	ticker := time.NewTicker(expiryInterval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := vehicle.ExpireReservations(ctx, a.store)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			a.logger.Error(
+				"Could not expire the reservations",
+				zap.Error(err),
+			)
+		case expired > 0:
+			a.logger.Info(
+				"Expired the reservations",
+				zap.Int("count", expired),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func (a *App) Close() error {
 	a.logger.Info("Server is stopping, see you next time!")
 
@@ -9,7 +9,13 @@ import (
 
 	"github.com/Cirederf1/vehicle-server/pkg/httputil"
 	"github.com/Cirederf1/vehicle-server/pkg/testutil"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/reservationstore/reservationstoretest"
+	"github.com/Cirederf1/vehicle-server/storage/storagetest"
+	"github.com/Cirederf1/vehicle-server/storage/telemetrystore/telemetrystoretest"
+	"github.com/Cirederf1/vehicle-server/storage/tripstore/tripstoretest"
 	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore/vehiclestoretest"
 	"github.com/Cirederf1/vehicle-server/vehicle"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -49,19 +55,22 @@ func TestApp_CreatesVehicles(t *testing.T) {
 				Longitude:    newVehicle.Longitude,
 				ShortCode:    newVehicle.ShortCode,
 				BatteryLevel: newVehicle.BatteryLevel,
+				Status:       "available",
+				Type:         "scooter",
 			},
 		}
 	)
 
 	err = httputil.DecodeJSON(resp.Body, &gotResponse)
 	require.NoError(t, err)
+	gotResponse.Vehicle = withoutTimestamps(t, gotResponse.Vehicle)
 	assert.Equal(t, wantResponse, gotResponse)
 }
 
 var vehicleSeed = []vehiclestore.Vehicle{
-	{Position: vehiclestore.Point{Latitude: 50.0, Longitude: 50.0}, ShortCode: "aaa", BatteryLevel: 40},
-	{Position: vehiclestore.Point{Latitude: 51.0, Longitude: 51.0}, ShortCode: "bbb", BatteryLevel: 50},
-	{Position: vehiclestore.Point{Latitude: 52.0, Longitude: 52.0}, ShortCode: "ccc", BatteryLevel: 60},
+	{Position: vehiclestore.Point{Latitude: 50.0, Longitude: 50.0}, ShortCode: "aaa", BatteryLevel: 40, Status: "available"},
+	{Position: vehiclestore.Point{Latitude: 51.0, Longitude: 51.0}, ShortCode: "bbb", BatteryLevel: 50, Status: "available"},
+	{Position: vehiclestore.Point{Latitude: 52.0, Longitude: 52.0}, ShortCode: "ccc", BatteryLevel: 60, Status: "available"},
 }
 
 func TestApp_ListsClosestVehicles(t *testing.T) {
@@ -88,17 +97,64 @@ func TestApp_ListsClosestVehicles(t *testing.T) {
 	// Decode the response body, and make assertions on the body.
 	var (
 		gotResponse  vehicle.ListResponse
-		wantResponse = vehicle.ListResponse{
-			Vehicles: []vehicle.Vehicle{
-				{ID: 1, Latitude: 50.0, Longitude: 50.0, ShortCode: "aaa", BatteryLevel: 40},
-				{ID: 2, Latitude: 51.0, Longitude: 51.0, ShortCode: "bbb", BatteryLevel: 50},
-				{ID: 3, Latitude: 52.0, Longitude: 52.0, ShortCode: "ccc", BatteryLevel: 60},
-			},
+		wantVehicles = []vehicle.Vehicle{
+			{ID: 1, Latitude: 50.0, Longitude: 50.0, ShortCode: "aaa", BatteryLevel: 40, Status: "available", Type: "scooter"},
+			{ID: 2, Latitude: 51.0, Longitude: 51.0, ShortCode: "bbb", BatteryLevel: 50, Status: "available", Type: "scooter"},
+			{ID: 3, Latitude: 52.0, Longitude: 52.0, ShortCode: "ccc", BatteryLevel: 60, Status: "available", Type: "scooter"},
 		}
+		wantDistances = []float64{132_585, 264_348, 395_272}
 	)
 
 	err = httputil.DecodeJSON(resp.Body, &gotResponse)
 	require.NoError(t, err)
+	require.Len(t, gotResponse.Vehicles, len(wantVehicles))
+
+	for i, v := range gotResponse.Vehicles {
+		assert.Equal(t, wantVehicles[i], withoutTimestamps(t, v.Vehicle))
+
+		// PostGIS and the haversine formula agree within a few meters.
+		require.NotNil(t, v.Distance)
+		require.NotNil(t, v.Bearing)
+		assert.InDelta(t, wantDistances[i], *v.Distance, 10)
+		assert.InDelta(t, 32, *v.Bearing, 1)
+	}
+}
+
+func TestApp_GetsVehicleByID(t *testing.T) {
+	t.Parallel()
+
+	// Setup the testenvironment, and clean it up as soon as the test finishes.
+	app, teardown := setupEnvironment(t)
+	t.Cleanup(teardown)
+
+	// Add some vehicles to the database.
+	seedVehicles(
+		t,
+		app.Store().Vehicle(),
+		vehicleSeed...,
+	)
+
+	// Make a request for a vehicle that does not exists.
+	// We should get a 404.
+	resp, err := http.Get("http://" + app.ListenAddress() + "/vehicles/456")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Make a request for a vehicle that does exist.
+	resp, err = http.Get("http://" + app.ListenAddress() + "/vehicles/2")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var (
+		gotResponse  vehicle.GetResponse
+		wantResponse = vehicle.GetResponse{
+			Vehicle: vehicle.Vehicle{ID: 2, Latitude: 51.0, Longitude: 51.0, ShortCode: "bbb", BatteryLevel: 50, Status: "available", Type: "scooter"},
+		}
+	)
+
+	err = httputil.DecodeJSON(resp.Body, &gotResponse)
+	require.NoError(t, err)
+	gotResponse.Vehicle = withoutTimestamps(t, gotResponse.Vehicle)
 	assert.Equal(t, wantResponse, gotResponse)
 }
 
@@ -131,38 +187,102 @@ func TestApp_DeletesVehicleByID(t *testing.T) {
 	require.Equal(t, http.StatusNoContent, resp.StatusCode)
 
 	// List all vehicles, we should have only two.
-	vehicles, err := app.Store().Vehicle().FindClosestFrom(
+	vehicles, _, err := app.Store().Vehicle().FindClosestFrom(
 		context.Background(),
 		vehiclestore.Point{
 			Longitude: 10.0,
 			Latitude:  10.0,
 		},
-		10,
+		vehiclestore.ListOptions{Limit: 10},
 	)
 	require.NoError(t, err)
-	assert.Equal(t,
-		[]vehiclestore.Vehicle{
-			{
-				ID:        2,
-				ShortCode: "bbb",
-				Position: vehiclestore.Point{
-					Latitude:  51,
-					Longitude: 51,
-				},
-				BatteryLevel: 50,
-			},
-			{
-				ID:        3,
-				ShortCode: "ccc",
-				Position: vehiclestore.Point{
-					Latitude:  52,
-					Longitude: 52,
-				},
-				BatteryLevel: 60,
-			},
-		},
-		vehicles,
+	require.Len(t, vehicles, 2)
+	assert.Equal(t, []int64{2, 3}, []int64{vehicles[0].ID, vehicles[1].ID})
+}
+
+func TestPGXVehicleStore(t *testing.T) {
+	vehiclestoretest.Run(t, func(t *testing.T) vehiclestore.Store {
+		// Setup the testenvironment, and clean it up as soon as the test finishes.
+		app, teardown := setupEnvironment(t)
+		t.Cleanup(teardown)
+
+		return app.Store().Vehicle()
+	})
+}
+
+func TestPGXTelemetryStore(t *testing.T) {
+	telemetrystoretest.Run(t, func(t *testing.T) storage.Store {
+		// Setup the testenvironment, and clean it up as soon as the test finishes.
+		app, teardown := setupEnvironment(t)
+		t.Cleanup(teardown)
+
+		return app.Store()
+	})
+}
+
+func TestPGXReservationStore(t *testing.T) {
+	reservationstoretest.Run(t, func(t *testing.T) storage.Store {
+		// Setup the testenvironment, and clean it up as soon as the test finishes.
+		app, teardown := setupEnvironment(t)
+		t.Cleanup(teardown)
+
+		return app.Store()
+	})
+}
+
+func TestPGXTripStore(t *testing.T) {
+	tripstoretest.Run(t, func(t *testing.T) storage.Store {
+		// Setup the testenvironment, and clean it up as soon as the test finishes.
+		app, teardown := setupEnvironment(t)
+		t.Cleanup(teardown)
+
+		return app.Store()
+	})
+}
+
+func TestPGXStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		// Setup the testenvironment, and clean it up as soon as the test finishes.
+		app, teardown := setupEnvironment(t)
+		t.Cleanup(teardown)
+
+		return app.Store()
+	})
+}
+
+func TestPGXMigrations(t *testing.T) {
+	t.Parallel()
+
+	// Setup the testenvironment, and clean it up as soon as the test finishes.
+	app, teardown := setupEnvironment(t)
+	t.Cleanup(teardown)
+
+	var (
+		ctx      = context.Background()
+		migrator = app.Store().(*storage.PGXStore).Migrator()
 	)
+
+	// Every migration is applied on startup.
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt, "migration %d", status.Version)
+	}
+
+	// Reverting marks the last migration as pending.
+	require.NoError(t, migrator.Down(ctx))
+
+	statuses, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, statuses[len(statuses)-1].AppliedAt)
+
+	// Applying again is idempotent.
+	require.NoError(t, migrator.Up(ctx))
+	require.NoError(t, migrator.Up(ctx))
+
+	statuses, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, statuses[len(statuses)-1].AppliedAt)
 }
 
 func httpDelete(url string) (*http.Response, error) {
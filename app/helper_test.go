@@ -9,6 +9,7 @@ import (
 
 	"github.com/Cirederf1/vehicle-server/app"
 	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/vehicle"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
@@ -53,10 +54,44 @@ func setupEnvironment(t *testing.T) (*app.App, func()) {
 	dbURL, err := database.ConnectionString(ctx, "sslmode=disable", "application_name=test")
 	require.NoError(t, err)
 
+	app, stopApp := runApp(
+		t,
+		app.Config{Storage: app.StoragePostgres, DatabaseURL: dbURL, ListenAddress: listenAddress},
+		logger,
+	)
+
+	// Declares the teardown function,
+	// done as an anonymous function to capture the necesary variables.
+	tearDownEnvironment := func() {
+		stopApp()
+
+		err = database.Stop(context.Background(), nil)
+		assert.NoError(t, err)
+	}
+
+	return app, tearDownEnvironment
+}
+
+// setupMemoryEnvironment runs the app on top of the in-memory storage,
+// it does not need any external dependency.
+func setupMemoryEnvironment(t *testing.T) (*app.App, func()) {
+	t.Helper()
+
+	return runApp(
+		t,
+		app.Config{Storage: app.StorageMemory, ListenAddress: listenAddress},
+		zaptest.NewLogger(t),
+	)
+}
+
+// runApp starts the app in the background and waits until it is ready to accept requests.
+func runApp(t *testing.T, cfg app.Config, logger *zap.Logger) (*app.App, func()) {
+	t.Helper()
+
 	appExited := make(chan struct{})
 
-	ctx, cancel := context.WithCancel(ctx)
-	app, err := app.New(ctx, app.Config{DatabaseURL: dbURL, ListenAddress: listenAddress}, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	app, err := app.New(ctx, cfg, logger)
 	require.NoError(t, err)
 
 	// Start asynchronously the server.
@@ -69,9 +104,7 @@ func setupEnvironment(t *testing.T) (*app.App, func()) {
 		close(appExited)
 	}()
 
-	// Declares the teardown function,
-	// done as an anonymous function to capture the necesary variables.
-	tearDownEnvironment := func() {
+	stopApp := func() {
 		logger.Info("Cleaning up the test environment")
 
 		cancel()
@@ -80,9 +113,6 @@ func setupEnvironment(t *testing.T) (*app.App, func()) {
 
 		err := app.Close()
 		assert.NoError(t, err)
-
-		err = database.Stop(context.Background(), nil)
-		assert.NoError(t, err)
 	}
 
 	// Poll the ready endpoint until the app is ready to accept requests.
@@ -96,7 +126,7 @@ func setupEnvironment(t *testing.T) (*app.App, func()) {
 
 		select {
 		case <-ctx.Done():
-			return app, tearDownEnvironment
+			return app, stopApp
 		case <-time.After(100 * time.Millisecond):
 			continue
 		}
@@ -104,7 +134,7 @@ func setupEnvironment(t *testing.T) (*app.App, func()) {
 
 	logger.Info("App is ready")
 
-	return app, tearDownEnvironment
+	return app, stopApp
 }
 
 func withLogger(l *zap.Logger) testcontainers.CustomizeRequestOption {
@@ -130,3 +160,16 @@ func seedVehicles(t *testing.T, store vehiclestore.Store, vehicles ...vehiclesto
 		require.NoError(t, err)
 	}
 }
+
+// withoutTimestamps checks the timestamps set by the store, then clears them
+// for the vehicle to compare equal to the expected one.
+func withoutTimestamps(t *testing.T, v vehicle.Vehicle) vehicle.Vehicle {
+	t.Helper()
+
+	assert.False(t, v.CreatedAt.IsZero())
+	assert.False(t, v.UpdatedAt.Before(v.CreatedAt))
+
+	v.CreatedAt, v.UpdatedAt = time.Time{}, time.Time{}
+
+	return v
+}
@@ -0,0 +1,85 @@
+//go:build !integration
+
+package app_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/app"
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/pkg/testutil"
+	"github.com/Cirederf1/vehicle-server/vehicle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestApp_MemoryStorage(t *testing.T) {
+	t.Parallel()
+
+	// Setup the testenvironment, and clean it up as soon as the test finishes.
+	app, teardown := setupMemoryEnvironment(t)
+	t.Cleanup(teardown)
+
+	newVehicle := vehicle.CreateRequest{
+		Latitude:     10.0,
+		Longitude:    9.0,
+		ShortCode:    "ebvf",
+		BatteryLevel: 72,
+	}
+
+	resp, err := http.Post(
+		"http://"+app.ListenAddress()+"/vehicles",
+		"application/json",
+		testutil.EncodeJSON(t, &newVehicle),
+	)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp, err = http.Get(
+		"http://" + app.ListenAddress() + "/vehicles?latitude=10.0&longitude=9.0&limit=10",
+	)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var (
+		gotResponse  vehicle.ListResponse
+		wantResponse = vehicle.ListResponse{
+			Vehicles: []vehicle.ListedVehicle{
+				{
+					Vehicle:  vehicle.Vehicle{ID: 1, Latitude: 10.0, Longitude: 9.0, ShortCode: "ebvf", BatteryLevel: 72, Status: "available", Type: "scooter"},
+					Distance: new(float64),
+				},
+			},
+		}
+	)
+
+	err = httputil.DecodeJSON(resp.Body, &gotResponse)
+	require.NoError(t, err)
+	for i, v := range gotResponse.Vehicles {
+		gotResponse.Vehicles[i].Vehicle = withoutTimestamps(t, v.Vehicle)
+	}
+	assert.Equal(t, wantResponse, gotResponse)
+}
+
+func TestApp_RequiresEveryTariff(t *testing.T) {
+	t.Parallel()
+
+	tariffs := filepath.Join(t.TempDir(), "tariffs.json")
+	require.NoError(t, os.WriteFile(
+		tariffs,
+		[]byte(`{"tariffs": {"scooter": {"currency": "EUR", "average_speed": 15}, "bike": {"currency": "EUR", "average_speed": 15}}}`),
+		0o600,
+	))
+
+	_, err := app.New(
+		context.Background(),
+		app.Config{Storage: app.StorageMemory, ListenAddress: listenAddress, TariffsFile: tariffs},
+		zaptest.NewLogger(t),
+	)
+	assert.ErrorContains(t, err, "missing the tariff of the moped vehicles")
+}
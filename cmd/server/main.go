@@ -3,11 +3,16 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
+	"math"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/Cirederf1/vehicle-server/app"
+	"github.com/Cirederf1/vehicle-server/pkg/shortcode"
 	"go.uber.org/zap"
 )
 
@@ -19,13 +24,46 @@ func run() int {
 
 	var cfg app.Config
 
+	flag.StringVar(&cfg.Storage, "storage", app.StoragePostgres, "Storage backend, one of postgres or memory")
 	flag.StringVar(&cfg.DatabaseURL, "database-url", "", "URL of the database")
 	flag.StringVar(&cfg.ListenAddress, "listen-address", ":8080", "Address to listen to")
+	flag.StringVar(&cfg.CursorSecret, "cursor-secret", os.Getenv("CURSOR_SECRET"), "Secret signing the pagination cursors, defaults to $CURSOR_SECRET")
+
+	var maxConns int
+	flag.IntVar(&maxConns, "database-max-conns", 0, "Maximum size of the database connection pool, 0 keeps the default")
+	flag.DurationVar(&cfg.DatabaseMaxConnIdleTime, "database-max-conn-idle-time", 0, "Duration after which an idle database connection is closed, 0 keeps the default")
+	flag.DurationVar(&cfg.DatabaseMaxConnLifetime, "database-max-conn-lifetime", 0, "Duration after which a database connection is closed, 0 keeps the default")
+	flag.Int64Var(&cfg.MaxListLimit, "max-list-limit", app.DefaultMaxListLimit, "Maximum number of vehicles listed per page")
+	flag.StringVar(&cfg.ShortCodeAlphabet, "shortcode-alphabet", shortcode.DefaultAlphabet, "Characters of the generated shortcodes, without ambiguous ones")
+
+	var shortCodeDenyList string
+	flag.StringVar(&shortCodeDenyList, "shortcode-deny-list", "", "Comma separated words the generated shortcodes must not contain, empty disables the deny-list, the default list applies when unset")
+	flag.DurationVar(&cfg.DeletedRetention, "deleted-retention", 30*24*time.Hour, "Duration the deleted vehicles can be restored for before being purged, 0 keeps them forever")
+	flag.DurationVar(&cfg.ReservationHold, "reservation-hold", app.DefaultReservationHold, "Duration the reservations hold their vehicle before expiring")
+	flag.StringVar(&cfg.TariffsFile, "tariffs", "", "Path of the JSON tariffs of the vehicle types, empty keeps the default ones")
+	flag.BoolVar(&cfg.DatabaseSkipMigrations, "database-skip-migrations", false, "Do not apply the pending database migrations on startup")
 
 	flag.Parse()
 
+	// The pool size is an int32, the larger values would wrap around.
+	if maxConns < 0 || maxConns > math.MaxInt32 {
+		fmt.Fprintf(os.Stderr, "invalid -database-max-conns %d: must be >= 0 and <= %d\n", maxConns, math.MaxInt32)
+		return 2
+	}
+	cfg.DatabaseMaxConns = int32(maxConns)
+	// The default deny-list applies only when the flag is unset, an empty one disables it.
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "shortcode-deny-list" {
+			cfg.ShortCodeDenyList = strings.Split(shortCodeDenyList, ",")
+		}
+	})
+
 	logger := zap.Must(zap.NewDevelopment())
 
+	if flag.Arg(0) == "migrate" {
+		return migrate(ctx, cfg, flag.Args()[1:], logger)
+	}
+
 	app, err := app.New(ctx, cfg, logger)
 	if err != nil {
 		return 1
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/app"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"go.uber.org/zap"
+)
+
+const migrateUsage = "usage: server [flags] migrate up|down|status"
+
+// migrate runs the migrate subcommand, args are the arguments following "migrate".
+func migrate(ctx context.Context, cfg app.Config, args []string, logger *zap.Logger) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	store, err := storage.NewPGXStore(
+		ctx,
+		storage.PGXConfig{DatabaseURL: cfg.DatabaseURL, SkipMigrations: true},
+		logger,
+	)
+	if err != nil {
+		logger.Error("Could not connect to the database", zap.Error(err))
+		return 1
+	}
+
+	defer func() {
+		_ = store.Close()
+	}()
+
+	migrator := store.Migrator()
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "status":
+		err = printStatus(ctx, store)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	if err != nil {
+		logger.Error("Could not run the migrations", zap.String("command", args[0]), zap.Error(err))
+		return 1
+	}
+
+	return 0
+}
+
+func printStatus(ctx context.Context, store *storage.PGXStore) error {
+	statuses, err := store.Migrator().Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,82 @@
+// Package cursor encodes pagination cursors as opaque, tamper proof strings.
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrInvalid is returned when decoding a malformed or forged cursor.
+var ErrInvalid = errors.New("invalid cursor")
+
+// Codec signs the cursors it encodes with HMAC-SHA256,
+// and only decodes the cursors it signed.
+type Codec struct {
+	key []byte
+}
+
+func NewCodec(key []byte) *Codec {
+	return &Codec{key: key}
+}
+
+// NewRandomCodec returns a codec with a random key,
+// its cursors are only valid for the lifetime of the process.
+func NewRandomCodec() (*Codec, error) {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return NewCodec(key), nil
+}
+
+// Encode returns the signed cursor of the JSON representation of v.
+func (c *Codec) Encode(v any) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(c.sign(payload)), nil
+}
+
+// Decode verifies the cursor signature and decodes its payload in v.
+func (c *Codec) Decode(cursor string, v any) error {
+	encodedPayload, encodedSignature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return ErrInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return ErrInvalid
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return ErrInvalid
+	}
+
+	if !hmac.Equal(signature, c.sign(payload)) {
+		return ErrInvalid
+	}
+
+	if err := json.Unmarshal(payload, v); err != nil {
+		return ErrInvalid
+	}
+
+	return nil
+}
+
+func (c *Codec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,42 @@
+//go:build !integration
+
+package cursor_test
+
+import (
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/pkg/cursor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type payload struct {
+	Distance float64 `json:"d"`
+	ID       int64   `json:"id"`
+}
+
+func TestCodec(t *testing.T) {
+	codec := cursor.NewCodec([]byte("secret"))
+
+	encoded, err := codec.Encode(payload{Distance: 1234.5678901234567, ID: 42})
+	require.NoError(t, err)
+
+	var got payload
+	require.NoError(t, codec.Decode(encoded, &got))
+	assert.Equal(t, payload{Distance: 1234.5678901234567, ID: 42}, got)
+
+	// A cursor signed with another key is rejected.
+	err = cursor.NewCodec([]byte("other")).Decode(encoded, &got)
+	assert.ErrorIs(t, err, cursor.ErrInvalid)
+
+	// So is a tampered one.
+	forged, err := cursor.NewCodec([]byte("other")).Encode(payload{ID: 1})
+	require.NoError(t, err)
+	err = codec.Decode(forged, &got)
+	assert.ErrorIs(t, err, cursor.ErrInvalid)
+
+	for _, malformed := range []string{"", "abc", "abc.def", "!!.!!"} {
+		err = codec.Decode(malformed, &got)
+		assert.ErrorIs(t, err, cursor.ErrInvalid, malformed)
+	}
+}
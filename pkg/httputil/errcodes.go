@@ -12,4 +12,7 @@ const (
 	// [1000 - 1999]: application level errors
 	ErrCodeInvalidRequestPayload = iota + 1000
 	ErrCodeResourceNotFound
+	ErrCodeResourceConflict
+	ErrCodeInvalidStatusTransition
+	ErrCodeResourceExhausted
 )
@@ -1,25 +1,162 @@
 package httputil
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
 )
 
+var errNotJSONArray = errors.New("request body is not a JSON array")
+
+// MaxNDJSONLineSize bounds the size in bytes of a line of the NDJSON request bodies,
+// each line being held in memory while it is decoded.
+const MaxNDJSONLineSize = 1 << 20
+
+// ErrNDJSONLineTooLong is given to the decode function of DecodeRequestAsNDJSON
+// in place of the lines longer than MaxNDJSONLineSize.
+var ErrNDJSONLineTooLong = errors.New("NDJSON line too long")
+
 var errTrailingGarbage = &APIError{
 	Code:    ErrCodeRequestBodyTrailingGarbage,
 	Message: "Unexpected garbage at the end on the request body",
 }
 
+const (
+	contentTypeJSON       = "application/json"
+	contentTypeMergePatch = "application/merge-patch+json"
+	contentTypeNDJSON     = "application/x-ndjson"
+)
+
 func DecodeRequestAsJSON(r *http.Request, v any) error {
-	if ct := r.Header.Get("Content-Type"); !strings.EqualFold(ct, "application/json") {
-		return unexpectedRequestContentTypeError(ct)
+	if ct := r.Header.Get("Content-Type"); !strings.EqualFold(ct, contentTypeJSON) {
+		return unexpectedRequestContentTypeError(contentTypeJSON, ct)
+	}
+
+	return DecodeJSON(r.Body, v)
+}
+
+// DecodeRequestAsMergePatch decodes a JSON Merge Patch (RFC 7386) request body.
+// Applying the patch is left to the caller.
+func DecodeRequestAsMergePatch(r *http.Request, v any) error {
+	if ct := r.Header.Get("Content-Type"); !strings.EqualFold(ct, contentTypeMergePatch) {
+		return unexpectedRequestContentTypeError(contentTypeMergePatch, ct)
 	}
 
 	return DecodeJSON(r.Body, v)
 }
 
+// DecodeRequestAsNDJSON reads a newline delimited JSON request body, and calls decode
+// with the number of each non blank line, starting at 1, and its content.
+// Decoding the lines is left to decode, so that each of them can fail on its own.
+// The lines longer than MaxNDJSONLineSize are skipped, decode is called with
+// ErrNDJSONLineTooLong instead of their content, and the reading goes on.
+// The reading stops at the first error returned by decode, which is returned.
+func DecodeRequestAsNDJSON(r *http.Request, decode func(line int, raw []byte, err error) error) error {
+	if ct := r.Header.Get("Content-Type"); !strings.EqualFold(ct, contentTypeNDJSON) {
+		return unexpectedRequestContentTypeError(contentTypeNDJSON, ct)
+	}
+
+	defer r.Body.Close()
+
+	var (
+		reader = bufio.NewReader(r.Body)
+		buf    []byte
+	)
+
+	for line := 1; ; line++ {
+		var (
+			tooLong bool
+			err     error
+		)
+
+		buf, tooLong, err = readNDJSONLine(reader, buf[:0])
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		var decodeErr error
+		if tooLong {
+			decodeErr = decode(line, nil, ErrNDJSONLineTooLong)
+		} else if raw := bytes.TrimSpace(buf); len(raw) > 0 {
+			decodeErr = decode(line, raw, nil)
+		}
+		if decodeErr != nil {
+			return decodeErr
+		}
+
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// readNDJSONLine appends the next line of reader to buf, line feed included.
+// The lines longer than MaxNDJSONLineSize are read up to their end, but not appended.
+// It returns io.EOF with the last line.
+func readNDJSONLine(reader *bufio.Reader, buf []byte) (_ []byte, tooLong bool, _ error) {
+	for {
+		chunk, err := reader.ReadSlice('\n')
+
+		if !tooLong {
+			buf = append(buf, chunk...)
+
+			if tooLong = len(bytes.TrimSuffix(buf, []byte("\n"))) > MaxNDJSONLineSize; tooLong {
+				buf = buf[:0]
+			}
+		}
+
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return buf, tooLong, err
+		}
+	}
+}
+
+// DecodeRequestAsJSONArray reads a JSON array request body one element at a time, and calls decode
+// with the index of each element, starting at 0, and its content.
+// The reading stops at the first error returned by decode, which is returned.
+func DecodeRequestAsJSONArray(r *http.Request, decode func(index int, raw json.RawMessage) error) error {
+	if ct := r.Header.Get("Content-Type"); !strings.EqualFold(ct, contentTypeJSON) {
+		return unexpectedRequestContentTypeError(contentTypeJSON, ct)
+	}
+
+	defer r.Body.Close()
+
+	dec := json.NewDecoder(r.Body)
+
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('[') {
+		return errNotJSONArray
+	}
+
+	for index := 0; dec.More(); index++ {
+		var raw json.RawMessage
+
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+
+		if err := decode(index, raw); err != nil {
+			return err
+		}
+	}
+
+	// The closing bracket.
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	if dec.More() {
+		return errTrailingGarbage
+	}
+
+	return nil
+}
+
 func DecodeJSON(body io.ReadCloser, v any) error {
 	defer body.Close()
 
@@ -42,12 +179,12 @@ func ServeJSON(rw http.ResponseWriter, statusCode int, payload any) {
 	_ = json.NewEncoder(rw).Encode(payload)
 }
 
-func unexpectedRequestContentTypeError(got string) error {
+func unexpectedRequestContentTypeError(expected, got string) error {
 	return &APIError{
 		Code:    ErrCodeRequestUnexpectedContentType,
 		Message: "Unexpected request content type",
 		Details: map[string]string{
-			"expected": "application/json",
+			"expected": expected,
 			"got":      got,
 		},
 	}
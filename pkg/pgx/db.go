@@ -5,14 +5,20 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// DB is the commonon ensemble of methods available between Tx and Conn.
+// DB is the commonon ensemble of methods available between Tx, Conn and Pool.
 // It allows to build transaction agnostic stores.
 type DB interface {
-	Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error)
-
 	Exec(ctx context.Context, sql string, arguments ...any) (commandTag pgconn.CommandTag, err error)
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
 }
+
+var (
+	_ DB = (*pgx.Conn)(nil)
+	_ DB = (pgx.Tx)(nil)
+	_ DB = (*pgxpool.Pool)(nil)
+)
@@ -0,0 +1,279 @@
+// Package pricing computes the fares of the trips from configurable tariffs.
+//
+// The amounts are integers in the minor unit of their currency, cents for euros,
+// so the fares add up without rounding errors.
+package pricing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ErrNoTariff is returned when pricing a vehicle type without a tariff.
+var ErrNoTariff = errors.New("no tariff for the vehicle type")
+
+// DefaultTariffs holds the tariffs of the vehicle types, unless configured.
+var DefaultTariffs = map[string]Tariff{
+	"scooter": {Currency: "EUR", UnlockFee: 100, PerMinute: 25, MaxFare: 3000, AverageSpeed: 15},
+	"bike":    {Currency: "EUR", UnlockFee: 100, PerMinute: 15, MaxFare: 2000, AverageSpeed: 15},
+	"moped":   {Currency: "EUR", UnlockFee: 100, PerMinute: 30, PerKilometer: 10, MaxFare: 5000, AverageSpeed: 30},
+}
+
+// Tariff tells how much a trip costs.
+type Tariff struct {
+	// Currency is the ISO 4217 code of the amounts.
+	Currency  string `json:"currency"`
+	UnlockFee int64  `json:"unlock_fee"`
+	// PerMinute is charged for every started minute.
+	PerMinute    int64 `json:"per_minute"`
+	PerKilometer int64 `json:"per_km"`
+	// MaxFare caps the fares, unlock fee included. Zero does not cap them.
+	MaxFare int64 `json:"max_fare"`
+	// Multipliers change the time and distance charges of the trips starting at some times of the day,
+	// the first one matching applies.
+	Multipliers []Multiplier `json:"multipliers,omitempty"`
+	// AverageSpeed is in km/h, it estimates the duration of the quoted trips.
+	AverageSpeed float64 `json:"average_speed"`
+}
+
+func (t Tariff) validate() error {
+	if len(t.Currency) != 3 {
+		return fmt.Errorf("invalid currency %q", t.Currency)
+	}
+
+	if t.UnlockFee < 0 || t.PerMinute < 0 || t.PerKilometer < 0 || t.MaxFare < 0 {
+		return errors.New("negative amount")
+	}
+
+	if t.MaxFare > 0 && t.MaxFare < t.UnlockFee {
+		return errors.New("max_fare is lower than the unlock fee")
+	}
+
+	if t.AverageSpeed <= 0 {
+		return errors.New("average_speed must be positive")
+	}
+
+	for _, m := range t.Multipliers {
+		if m.Factor <= 0 {
+			return fmt.Errorf("multiplier factor %v must be positive", m.Factor)
+		}
+
+		if m.From == m.To {
+			return fmt.Errorf("multiplier from %s to %s is empty", m.From, m.To)
+		}
+	}
+
+	return nil
+}
+
+// factor returns the factor of the first multiplier matching the time, 1 when none does.
+func (t Tariff) factor(at time.Time) float64 {
+	tod := timeOfDay(at)
+
+	for _, m := range t.Multipliers {
+		if m.contains(tod) {
+			return m.Factor
+		}
+	}
+
+	return 1
+}
+
+// Multiplier applies a factor to the trips starting from a time of the day included to another excluded,
+// it spans over midnight when To is before From.
+type Multiplier struct {
+	From   TimeOfDay `json:"from"`
+	To     TimeOfDay `json:"to"`
+	Factor float64   `json:"factor"`
+}
+
+func (m Multiplier) contains(tod TimeOfDay) bool {
+	if m.From < m.To {
+		return tod >= m.From && tod < m.To
+	}
+
+	return tod >= m.From || tod < m.To
+}
+
+// TimeOfDay is a number of minutes since midnight, written as 15:04.
+type TimeOfDay int
+
+const timeOfDayLayout = "15:04"
+
+func timeOfDay(t time.Time) TimeOfDay {
+	return TimeOfDay(t.Hour()*60 + t.Minute())
+}
+
+func (tod TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", tod/60, tod%60)
+}
+
+func (tod TimeOfDay) MarshalText() ([]byte, error) {
+	return []byte(tod.String()), nil
+}
+
+func (tod *TimeOfDay) UnmarshalText(text []byte) error {
+	t, err := time.Parse(timeOfDayLayout, string(text))
+	if err != nil {
+		return fmt.Errorf("invalid time of day %q, expected hh:mm", text)
+	}
+
+	*tod = timeOfDay(t)
+
+	return nil
+}
+
+// ItemKind tells what a line of a fare charges for.
+type ItemKind string
+
+const (
+	ItemUnlock   ItemKind = "unlock"
+	ItemTime     ItemKind = "time"
+	ItemDistance ItemKind = "distance"
+	// ItemTimeOfDay is the difference made by a multiplier, negative for a discount.
+	ItemTimeOfDay ItemKind = "time_of_day"
+	// ItemCap is the negative amount bringing the fare down to the max fare.
+	ItemCap ItemKind = "cap"
+)
+
+type Item struct {
+	Kind   ItemKind `json:"kind"`
+	Amount int64    `json:"amount"`
+}
+
+// Fare is the itemized price of a trip, its total is the sum of the items.
+type Fare struct {
+	Currency string `json:"currency"`
+	Items    []Item `json:"items"`
+	Total    int64  `json:"total"`
+}
+
+func (f *Fare) add(kind ItemKind, amount int64) {
+	f.Items = append(f.Items, Item{Kind: kind, Amount: amount})
+	f.Total += amount
+}
+
+// Ride is what a trip is priced on.
+type Ride struct {
+	Start    time.Time
+	Duration time.Duration
+	// Distance is in meters.
+	Distance float64
+}
+
+// Estimate is the expected ride and fare of a trip not made yet.
+type Estimate struct {
+	Ride Ride
+	Fare Fare
+}
+
+// Pricer prices the trips with the tariff of their vehicle type.
+type Pricer struct {
+	tariffs map[string]Tariff
+	// location tells the time of day of the multipliers.
+	location *time.Location
+}
+
+// NewPricer returns a pricer of the tariffs keyed by vehicle type,
+// the times of day of the multipliers are the ones of the location, UTC when nil.
+func NewPricer(tariffs map[string]Tariff, location *time.Location) (*Pricer, error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	for vehicleType, tariff := range tariffs {
+		if err := tariff.validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s tariff: %w", vehicleType, err)
+		}
+	}
+
+	return &Pricer{tariffs: tariffs, location: location}, nil
+}
+
+// Load reads a pricer from a JSON document holding the time zone and the tariffs:
+//
+//	{"time_zone": "Europe/Paris", "tariffs": {"scooter": {"currency": "EUR", ...}}}
+//
+// The time zone is UTC when empty.
+func Load(r io.Reader) (*Pricer, error) {
+	var doc struct {
+		TimeZone string            `json:"time_zone"`
+		Tariffs  map[string]Tariff `json:"tariffs"`
+	}
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("could not decode the tariffs: %w", err)
+	}
+
+	location, err := time.LoadLocation(doc.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPricer(doc.Tariffs, location)
+}
+
+// Has tells whether the vehicle type has a tariff.
+func (p *Pricer) Has(vehicleType string) bool {
+	_, ok := p.tariffs[vehicleType]
+	return ok
+}
+
+// Fare returns the fare of a ride with a vehicle of the type, or ErrNoTariff.
+// The time of day of the start of the ride selects the multiplier.
+func (p *Pricer) Fare(vehicleType string, ride Ride) (Fare, error) {
+	tariff, ok := p.tariffs[vehicleType]
+	if !ok {
+		return Fare{}, ErrNoTariff
+	}
+
+	var (
+		fare           = Fare{Currency: tariff.Currency}
+		minutes        = int64(math.Ceil(ride.Duration.Minutes()))
+		timeCharge     = minutes * tariff.PerMinute
+		distanceCharge = int64(math.Round(ride.Distance / 1000 * float64(tariff.PerKilometer)))
+	)
+
+	fare.add(ItemUnlock, tariff.UnlockFee)
+	fare.add(ItemTime, timeCharge)
+	fare.add(ItemDistance, distanceCharge)
+
+	if factor := tariff.factor(ride.Start.In(p.location)); factor != 1 {
+		fare.add(ItemTimeOfDay, int64(math.Round(float64(timeCharge+distanceCharge)*(factor-1))))
+	}
+
+	if tariff.MaxFare > 0 && fare.Total > tariff.MaxFare {
+		fare.add(ItemCap, tariff.MaxFare-fare.Total)
+	}
+
+	return fare, nil
+}
+
+// Quote estimates the fare of a ride with a vehicle of the type starting at a time,
+// the duration follows from the distance in meters and the average speed of the tariff.
+func (p *Pricer) Quote(vehicleType string, start time.Time, distance float64) (Estimate, error) {
+	tariff, ok := p.tariffs[vehicleType]
+	if !ok {
+		return Estimate{}, ErrNoTariff
+	}
+
+	ride := Ride{
+		Start:    start,
+		Duration: time.Duration(distance / 1000 / tariff.AverageSpeed * float64(time.Hour)).Round(time.Second),
+		Distance: distance,
+	}
+
+	fare, err := p.Fare(vehicleType, ride)
+	if err != nil {
+		return Estimate{}, err
+	}
+
+	return Estimate{Ride: ride, Fare: fare}, nil
+}
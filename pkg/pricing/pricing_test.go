@@ -0,0 +1,174 @@
+//go:build !integration
+
+package pricing_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/pricing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noon is a time without multiplier.
+var noon = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+func TestPricerFare(t *testing.T) {
+	tariff := pricing.Tariff{
+		Currency:     "EUR",
+		UnlockFee:    100,
+		PerMinute:    25,
+		PerKilometer: 10,
+		MaxFare:      2000,
+		Multipliers: []pricing.Multiplier{
+			{From: 22 * 60, To: 6 * 60, Factor: 1.5},
+			{From: 14 * 60, To: 16 * 60, Factor: 0.8},
+		},
+		AverageSpeed: 15,
+	}
+
+	pricer, err := pricing.NewPricer(map[string]pricing.Tariff{"scooter": tariff}, nil)
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		desc     string
+		ride     pricing.Ride
+		wantFare pricing.Fare
+	}{
+		{
+			desc: "started minutes",
+			ride: pricing.Ride{Start: noon, Duration: 10*time.Minute + time.Second, Distance: 2350},
+			wantFare: pricing.Fare{
+				Currency: "EUR",
+				Items: []pricing.Item{
+					{Kind: pricing.ItemUnlock, Amount: 100},
+					{Kind: pricing.ItemTime, Amount: 275},
+					{Kind: pricing.ItemDistance, Amount: 24},
+				},
+				Total: 399,
+			},
+		},
+		{
+			desc: "night surcharge over midnight",
+			ride: pricing.Ride{Start: noon.Add(11*time.Hour + 30*time.Minute), Duration: 10 * time.Minute, Distance: 2000},
+			wantFare: pricing.Fare{
+				Currency: "EUR",
+				Items: []pricing.Item{
+					{Kind: pricing.ItemUnlock, Amount: 100},
+					{Kind: pricing.ItemTime, Amount: 250},
+					{Kind: pricing.ItemDistance, Amount: 20},
+					{Kind: pricing.ItemTimeOfDay, Amount: 135},
+				},
+				Total: 505,
+			},
+		},
+		{
+			desc: "afternoon discount",
+			ride: pricing.Ride{Start: noon.Add(2 * time.Hour), Duration: 10 * time.Minute},
+			wantFare: pricing.Fare{
+				Currency: "EUR",
+				Items: []pricing.Item{
+					{Kind: pricing.ItemUnlock, Amount: 100},
+					{Kind: pricing.ItemTime, Amount: 250},
+					{Kind: pricing.ItemDistance, Amount: 0},
+					{Kind: pricing.ItemTimeOfDay, Amount: -50},
+				},
+				Total: 300,
+			},
+		},
+		{
+			desc: "capped",
+			ride: pricing.Ride{Start: noon, Duration: 2 * time.Hour, Distance: 20000},
+			wantFare: pricing.Fare{
+				Currency: "EUR",
+				Items: []pricing.Item{
+					{Kind: pricing.ItemUnlock, Amount: 100},
+					{Kind: pricing.ItemTime, Amount: 3000},
+					{Kind: pricing.ItemDistance, Amount: 200},
+					{Kind: pricing.ItemCap, Amount: -1300},
+				},
+				Total: 2000,
+			},
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			fare, err := pricer.Fare("scooter", testCase.ride)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.wantFare, fare)
+		})
+	}
+
+	_, err = pricer.Fare("moped", pricing.Ride{Start: noon})
+	assert.ErrorIs(t, err, pricing.ErrNoTariff)
+}
+
+func TestPricerQuote(t *testing.T) {
+	pricer, err := pricing.NewPricer(pricing.DefaultTariffs, nil)
+	require.NoError(t, err)
+
+	// 3 km at 15 km/h take 12 minutes.
+	estimate, err := pricer.Quote("scooter", noon, 3000)
+	require.NoError(t, err)
+	assert.Equal(t, pricing.Ride{Start: noon, Duration: 12 * time.Minute, Distance: 3000}, estimate.Ride)
+	assert.Equal(t, int64(400), estimate.Fare.Total)
+
+	_, err = pricer.Quote("tank", noon, 3000)
+	assert.ErrorIs(t, err, pricing.ErrNoTariff)
+}
+
+func TestLoad(t *testing.T) {
+	pricer, err := pricing.Load(strings.NewReader(`{
+		"time_zone": "Europe/Paris",
+		"tariffs": {
+			"bike": {
+				"currency": "EUR",
+				"unlock_fee": 50,
+				"per_minute": 10,
+				"multipliers": [{"from": "22:00", "to": "06:00", "factor": 2}],
+				"average_speed": 12
+			}
+		}
+	}`))
+	require.NoError(t, err)
+	assert.True(t, pricer.Has("bike"))
+	assert.False(t, pricer.Has("scooter"))
+
+	// 21:30 in UTC is 22:30 in Paris.
+	fare, err := pricer.Fare("bike", pricing.Ride{Start: noon.Add(9*time.Hour + 30*time.Minute), Duration: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, int64(70), fare.Total)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, testCase := range []struct {
+		desc    string
+		doc     string
+		wantErr string
+	}{
+		{desc: "malformed", doc: `{"tariffs": []}`, wantErr: "could not decode"},
+		{desc: "unknown field", doc: `{"tariff": {}}`, wantErr: "unknown field"},
+		{desc: "unknown time zone", doc: `{"time_zone": "Mars/Olympus"}`, wantErr: "unknown time zone"},
+		{desc: "malformed time of day", doc: `{"tariffs": {"bike": {"multipliers": [{"from": "25h"}]}}}`, wantErr: "invalid time of day"},
+		{desc: "missing currency", doc: `{"tariffs": {"bike": {"average_speed": 12}}}`, wantErr: "invalid bike tariff: invalid currency"},
+		{desc: "negative amount", doc: `{"tariffs": {"bike": {"currency": "EUR", "per_minute": -1, "average_speed": 12}}}`, wantErr: "negative amount"},
+		{desc: "cap below the unlock fee", doc: `{"tariffs": {"bike": {"currency": "EUR", "unlock_fee": 100, "max_fare": 50, "average_speed": 12}}}`, wantErr: "max_fare"},
+		{desc: "missing speed", doc: `{"tariffs": {"bike": {"currency": "EUR"}}}`, wantErr: "average_speed"},
+		{
+			desc:    "empty multiplier",
+			doc:     `{"tariffs": {"bike": {"currency": "EUR", "average_speed": 12, "multipliers": [{"from": "10:00", "to": "10:00", "factor": 2}]}}}`,
+			wantErr: "is empty",
+		},
+		{
+			desc:    "null factor",
+			doc:     `{"tariffs": {"bike": {"currency": "EUR", "average_speed": 12, "multipliers": [{"from": "10:00", "to": "11:00"}]}}}`,
+			wantErr: "must be positive",
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			_, err := pricing.Load(strings.NewReader(testCase.doc))
+			assert.ErrorContains(t, err, testCase.wantErr)
+		})
+	}
+}
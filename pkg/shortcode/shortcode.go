@@ -0,0 +1,121 @@
+// Package shortcode generates the random shortcodes riders type in to unlock a vehicle.
+package shortcode
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// DefaultAlphabet is made of the upper case letters and digits, without the ambiguous ones.
+const DefaultAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// Ambiguous lists the characters easily mistaken for one another, they cannot be part of an alphabet.
+const Ambiguous = "0O1Iilo"
+
+// DefaultDenyList holds the words a generated shortcode must not contain.
+// The words made of ambiguous characters can never be generated, and are left out.
+var DefaultDenyList = []string{
+	"ASS", "CUM", "CUNT", "DCK", "FAG", "FCK", "FUCK", "FUK", "KKK", "NAZ",
+	"NGR", "PD", "PUTE", "SEX", "SS", "TWAT", "WTF", "XXX",
+}
+
+// maxAttempts bounds the draws rejected by the deny-list.
+const maxAttempts = 100
+
+// ErrExhausted is returned when every draw contains a denied word.
+var ErrExhausted = errors.New("could not generate an allowed shortcode")
+
+// Generator draws shortcodes of a fixed length from an alphabet,
+// and rejects the ones containing a denied word.
+type Generator struct {
+	alphabet []rune
+	length   int
+	denyList []string
+}
+
+// NewGenerator returns a generator of length characters long shortcodes.
+// The alphabet must hold at least two distinct characters, and no ambiguous one.
+// The deny-list is matched case-insensitively anywhere in the shortcodes.
+func NewGenerator(alphabet string, length int, denyList []string) (*Generator, error) {
+	if length < 1 {
+		return nil, fmt.Errorf("invalid shortcode length %d", length)
+	}
+
+	var (
+		runes = []rune(alphabet)
+		seen  = make(map[rune]bool, len(runes))
+	)
+
+	for _, r := range runes {
+		if strings.ContainsRune(Ambiguous, r) {
+			return nil, fmt.Errorf("ambiguous character %q in the shortcode alphabet", r)
+		}
+
+		if seen[r] {
+			return nil, fmt.Errorf("duplicate character %q in the shortcode alphabet", r)
+		}
+		seen[r] = true
+	}
+
+	if len(runes) < 2 {
+		return nil, errors.New("the shortcode alphabet must have at least two characters")
+	}
+
+	denied := make([]string, 0, len(denyList))
+	for _, word := range denyList {
+		if word = strings.TrimSpace(word); word != "" {
+			denied = append(denied, strings.ToUpper(word))
+		}
+	}
+
+	return &Generator{alphabet: runes, length: length, denyList: denied}, nil
+}
+
+// Generate returns a random shortcode, it is up to the caller to check it is not used yet.
+func (g *Generator) Generate() (string, error) {
+	for range maxAttempts {
+		code, err := g.draw()
+		if err != nil {
+			return "", err
+		}
+
+		if !g.denied(code) {
+			return code, nil
+		}
+	}
+
+	return "", ErrExhausted
+}
+
+func (g *Generator) draw() (string, error) {
+	var (
+		code strings.Builder
+		max  = big.NewInt(int64(len(g.alphabet)))
+	)
+
+	for range g.length {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		code.WriteRune(g.alphabet[n.Int64()])
+	}
+
+	return code.String(), nil
+}
+
+func (g *Generator) denied(code string) bool {
+	code = strings.ToUpper(code)
+
+	for _, word := range g.denyList {
+		if strings.Contains(code, word) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,64 @@
+//go:build !integration
+
+package shortcode_test
+
+import (
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/pkg/shortcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator(t *testing.T) {
+	generator, err := shortcode.NewGenerator(shortcode.DefaultAlphabet, 4, shortcode.DefaultDenyList)
+	require.NoError(t, err)
+
+	for range 1000 {
+		code, err := generator.Generate()
+		require.NoError(t, err)
+		require.Len(t, code, 4)
+
+		for _, r := range code {
+			assert.Contains(t, shortcode.DefaultAlphabet, string(r))
+		}
+
+		for _, word := range shortcode.DefaultDenyList {
+			assert.NotContains(t, code, word)
+		}
+	}
+}
+
+func TestGeneratorDenyList(t *testing.T) {
+	// Only B can be drawn without a denied word.
+	generator, err := shortcode.NewGenerator("AB", 3, []string{" a "})
+	require.NoError(t, err)
+
+	code, err := generator.Generate()
+	require.NoError(t, err)
+	assert.Equal(t, "BBB", code)
+
+	// And nothing is allowed anymore.
+	generator, err = shortcode.NewGenerator("AB", 3, []string{"a", "b"})
+	require.NoError(t, err)
+
+	_, err = generator.Generate()
+	assert.ErrorIs(t, err, shortcode.ErrExhausted)
+}
+
+func TestNewGeneratorInvalid(t *testing.T) {
+	for _, testCase := range []struct {
+		desc     string
+		alphabet string
+		length   int
+		wantErr  string
+	}{
+		{desc: "ambiguous character", alphabet: "ABO", length: 4, wantErr: "ambiguous"},
+		{desc: "duplicate character", alphabet: "ABA", length: 4, wantErr: "duplicate"},
+		{desc: "single character", alphabet: "A", length: 4, wantErr: "at least two"},
+		{desc: "empty length", alphabet: "AB", length: 0, wantErr: "length"},
+	} {
+		_, err := shortcode.NewGenerator(testCase.alphabet, testCase.length, nil)
+		assert.ErrorContains(t, err, testCase.wantErr, testCase.desc)
+	}
+}
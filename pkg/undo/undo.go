@@ -0,0 +1,63 @@
+// Package undo records the previous values of the map entries changed in place,
+// so that the in-memory transactions can undo their changes.
+package undo
+
+// Log records the entries of a map as they were before being changed.
+// A nil Log records nothing, its changes cannot be undone.
+type Log[K comparable, V any] struct {
+	saved map[K]entry[V]
+}
+
+// entry is a saved map entry, ok tells whether the key was present.
+type entry[V any] struct {
+	value V
+	ok    bool
+}
+
+func New[K comparable, V any]() *Log[K, V] {
+	return &Log[K, V]{saved: make(map[K]entry[V])}
+}
+
+// Save records the entry of key in m, unless it was already recorded.
+// It must be called before each change of the entry.
+func (l *Log[K, V]) Save(m map[K]V, key K) {
+	if l == nil {
+		return
+	}
+
+	if _, ok := l.saved[key]; ok {
+		return
+	}
+
+	value, ok := m[key]
+	l.saved[key] = entry[V]{value: value, ok: ok}
+}
+
+// Undo restores the recorded entries in m.
+func (l *Log[K, V]) Undo(m map[K]V) {
+	if l == nil {
+		return
+	}
+
+	for key, e := range l.saved {
+		if e.ok {
+			m[key] = e.value
+		} else {
+			delete(m, key)
+		}
+	}
+}
+
+// Merge records the entries recorded by nested, the log of a nested transaction which committed,
+// so that its changes are undone along with the ones of l.
+func (l *Log[K, V]) Merge(nested *Log[K, V]) {
+	if l == nil || nested == nil {
+		return
+	}
+
+	for key, e := range nested.saved {
+		if _, ok := l.saved[key]; !ok {
+			l.saved[key] = e
+		}
+	}
+}
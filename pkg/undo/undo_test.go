@@ -0,0 +1,65 @@
+//go:build !integration
+
+package undo_test
+
+import (
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/pkg/undo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog(t *testing.T) {
+	var (
+		m   = map[string]int{"a": 1, "b": 2}
+		log = undo.New[string, int]()
+	)
+
+	log.Save(m, "a")
+	m["a"] = 10
+	log.Save(m, "a")
+	m["a"] = 100
+
+	log.Save(m, "b")
+	delete(m, "b")
+
+	log.Save(m, "c")
+	m["c"] = 3
+
+	log.Undo(m)
+	assert.Equal(t, map[string]int{"a": 1, "b": 2}, m)
+}
+
+func TestLogMerge(t *testing.T) {
+	var (
+		m      = map[string]int{"a": 1}
+		outer  = undo.New[string, int]()
+		nested = undo.New[string, int]()
+	)
+
+	outer.Save(m, "a")
+	m["a"] = 10
+
+	nested.Save(m, "a")
+	m["a"] = 100
+	nested.Save(m, "b")
+	m["b"] = 2
+
+	// The nested transaction commits, then the outer one rolls back.
+	outer.Merge(nested)
+	outer.Undo(m)
+	assert.Equal(t, map[string]int{"a": 1}, m)
+}
+
+func TestNilLog(t *testing.T) {
+	var (
+		m   = map[string]int{"a": 1}
+		log *undo.Log[string, int]
+	)
+
+	log.Save(m, "a")
+	m["a"] = 10
+
+	log.Undo(m)
+	assert.Equal(t, map[string]int{"a": 10}, m)
+}
@@ -1,15 +1,117 @@
 package storage
 
-import "github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+import (
+	"context"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/storage/reservationstore"
+	"github.com/Cirederf1/vehicle-server/storage/telemetrystore"
+	"github.com/Cirederf1/vehicle-server/storage/tripstore"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+)
 
 type MemoryStore struct {
-	VehicleStore *vehiclestore.MemoryStore
+	VehicleStore     *vehiclestore.MemoryStore
+	TelemetryStore   *telemetrystore.MemoryStore
+	ReservationStore *reservationstore.MemoryStore
+	TripStore        *tripstore.MemoryStore
 }
 
 func NewMemoryStore() *MemoryStore {
-	return &MemoryStore{VehicleStore: vehiclestore.NewMemoryStore()}
+	return NewMemoryStoreWithClock(time.Now)
+}
+
+// NewMemoryStoreWithClock returns a MemoryStore timestamping the changes with now.
+func NewMemoryStoreWithClock(now func() time.Time) *MemoryStore {
+	return &MemoryStore{
+		VehicleStore:     vehiclestore.NewMemoryStoreWithClock(now),
+		TelemetryStore:   telemetrystore.NewMemoryStore(),
+		ReservationStore: reservationstore.NewMemoryStoreWithClock(now),
+		TripStore:        tripstore.NewMemoryStore(),
+	}
 }
 
 func (m *MemoryStore) Vehicle() vehiclestore.Store {
-	return m.VehicleStore
+	return &memoryVehicleStore{MemoryStore: m.VehicleStore, store: m}
+}
+
+func (m *MemoryStore) Telemetry() telemetrystore.Store {
+	return m.TelemetryStore
+}
+
+func (m *MemoryStore) Reservation() reservationstore.Store {
+	return m.ReservationStore
+}
+
+func (m *MemoryStore) Trip() tripstore.Store {
+	return m.TripStore
+}
+
+// WithTx locks the underlying stores for the duration of fn, which changes them in place.
+// The changes are undone when fn fails.
+func (m *MemoryStore) WithTx(ctx context.Context, fn func(Store) error) error {
+	return m.withTx(func(tx *MemoryStore) error { return fn(tx) })
+}
+
+// withTx is WithTx, giving fn the concrete transactions of the stores.
+func (m *MemoryStore) withTx(fn func(*MemoryStore) error) error {
+	vehicleTx, endVehicleTx := m.VehicleStore.Begin()
+	telemetryTx, endTelemetryTx := m.TelemetryStore.Begin()
+	reservationTx, endReservationTx := m.ReservationStore.Begin()
+	tripTx, endTripTx := m.TripStore.Begin()
+
+	committed := false
+	defer func() {
+		endTripTx(committed)
+		endReservationTx(committed)
+		endTelemetryTx(committed)
+		endVehicleTx(committed)
+	}()
+
+	tx := &MemoryStore{
+		VehicleStore:     vehicleTx,
+		TelemetryStore:   telemetryTx,
+		ReservationStore: reservationTx,
+		TripStore:        tripTx,
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	committed = true
+
+	return nil
+}
+
+// memoryVehicleStore removes the records of the purged vehicles from the other stores,
+// as the foreign keys of the PostgreSQL store cascade their deletion.
+type memoryVehicleStore struct {
+	*vehiclestore.MemoryStore
+	store *MemoryStore
+}
+
+func (s *memoryVehicleStore) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged []int64
+
+	err := s.store.withTx(func(tx *MemoryStore) error {
+		var err error
+
+		purged, err = tx.VehicleStore.PurgeIDs(ctx, deletedBefore)
+		if err != nil {
+			return err
+		}
+
+		tx.TelemetryStore.DeleteVehicles(purged)
+		tx.ReservationStore.DeleteVehicles(purged)
+		tx.TripStore.DeleteVehicles(purged)
+
+		return nil
+	})
+
+	return int64(len(purged)), err
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
 }
@@ -0,0 +1,16 @@
+//go:build !integration
+
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/storagetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		return storage.NewMemoryStore()
+	})
+}
@@ -0,0 +1,315 @@
+// Package migrations applies the versioned SQL migrations of the database schema.
+//
+// Migrations live in the sql directory, named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, and are embedded in the binary.
+// The applied versions are tracked in the vehicle_server.schema_migrations table.
+package migrations
+
+import (
+	"cmp"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockKey identifies the advisory lock serializing migrations across replicas.
+const lockKey int64 = 7_283_946_154
+
+const createMigrationsTableStatement = `
+CREATE SCHEMA IF NOT EXISTS vehicle_server;
+CREATE TABLE IF NOT EXISTS vehicle_server.schema_migrations (
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+`
+
+// Migration is a versioned change of the database schema.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status tells whether a migration is applied.
+type Status struct {
+	Migration
+	// AppliedAt is nil when the migration is pending.
+	AppliedAt *time.Time
+}
+
+// Load returns the embedded migrations, ordered by version.
+func Load() ([]Migration, error) {
+	return load(files)
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.Glob(fsys, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+
+	for _, entry := range entries {
+		base := path.Base(entry)
+
+		var (
+			direction string
+			stem      string
+		)
+
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction, stem = "up", strings.TrimSuffix(base, ".up.sql")
+		case strings.HasSuffix(base, ".down.sql"):
+			direction, stem = "down", strings.TrimSuffix(base, ".down.sql")
+		default:
+			return nil, fmt.Errorf("migration %q: missing .up.sql or .down.sql suffix", base)
+		}
+
+		rawVersion, name, ok := strings.Cut(stem, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %q: expected <version>_<name>", base)
+		}
+
+		version, err := strconv.ParseInt(rawVersion, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %q: invalid version: %w", base, err)
+		}
+
+		content, err := fs.ReadFile(fsys, entry)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d: both up and down files are required", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+var errNothingToRevert = errors.New("no migration to revert")
+
+// Migrator applies the migrations to a database.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+func NewMigrator(pool *pgxpool.Pool, logger *zap.Logger) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, fmt.Errorf("could not load the migrations: %w", err)
+	}
+
+	return &Migrator{
+		pool:       pool,
+		migrations: migrations,
+		logger:     logger,
+	}, nil
+}
+
+// Up applies all the pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgx.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			m.logger.Info(
+				"Applying migration",
+				zap.Int64("version", migration.Version),
+				zap.String("name", migration.Name),
+			)
+
+			if err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Up); err != nil {
+					return err
+				}
+
+				_, err := tx.Exec(
+					ctx,
+					`INSERT INTO vehicle_server.schema_migrations (version, name) VALUES ($1, $2)`,
+					migration.Version,
+					migration.Name,
+				)
+				return err
+			}); err != nil {
+				return fmt.Errorf("could not apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Down reverts the last applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgx.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			m.logger.Info(
+				"Reverting migration",
+				zap.Int64("version", migration.Version),
+				zap.String("name", migration.Name),
+			)
+
+			if err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Down); err != nil {
+					return err
+				}
+
+				_, err := tx.Exec(
+					ctx,
+					`DELETE FROM vehicle_server.schema_migrations WHERE version = $1`,
+					migration.Version,
+				)
+				return err
+			}); err != nil {
+				return fmt.Errorf("could not revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			return nil
+		}
+
+		return errNothingToRevert
+	})
+}
+
+// Status returns every known migration along with when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+
+	err := m.withLock(ctx, func(conn *pgx.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if appliedAt, ok := applied[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+
+			statuses = append(statuses, status)
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// withLock runs do on a dedicated connection holding the migrations advisory lock,
+// so that concurrent replicas wait for each other instead of racing.
+func (m *Migrator) withLock(ctx context.Context, do func(*pgx.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("could not acquire a connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("could not take the migrations lock: %w", err)
+	}
+
+	defer func() {
+		// Use a fresh context, the lock must be released even if ctx is canceled.
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if _, err := conn.Exec(unlockCtx, `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+			m.logger.Error("Could not release the migrations lock", zap.Error(err))
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, createMigrationsTableStatement); err != nil {
+		return fmt.Errorf("could not create the migrations table: %w", err)
+	}
+
+	return do(conn.Conn())
+}
+
+func appliedVersions(ctx context.Context, conn *pgx.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM vehicle_server.schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
@@ -0,0 +1,59 @@
+//go:build !integration
+
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.Equal(t, int64(i+1), m.Version, "versions must be contiguous")
+		assert.NotEmpty(t, m.Up)
+		assert.NotEmpty(t, m.Down)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, testCase := range []struct {
+		desc    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{
+			desc: "missing down file",
+			files: fstest.MapFS{
+				"sql/0001_init.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			wantErr: "migration 1: both up and down files are required",
+		},
+		{
+			desc: "invalid version",
+			files: fstest.MapFS{
+				"sql/first_init.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			wantErr: `migration "first_init.up.sql": invalid version`,
+		},
+		{
+			desc: "conflicting names",
+			files: fstest.MapFS{
+				"sql/0001_init.up.sql":    {Data: []byte("SELECT 1;")},
+				"sql/0001_other.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			wantErr: `migration 1: conflicting names`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			_, err := load(testCase.files)
+			assert.ErrorContains(t, err, testCase.wantErr)
+		})
+	}
+}
@@ -0,0 +1,9 @@
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+-- Databases created before migrations existed already have this table.
+CREATE TABLE IF NOT EXISTS vehicle_server.vehicles (
+	id SERIAL PRIMARY KEY,
+	shortcode TEXT NOT NULL,
+	battery SMALLINT,
+	position GEOMETRY(POINT, 4326) not null
+);
@@ -0,0 +1 @@
+DROP INDEX vehicle_server.vehicles_position_geography_idx;
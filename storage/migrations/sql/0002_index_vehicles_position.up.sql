@@ -0,0 +1,4 @@
+-- Distance searches are computed on the geography, index the cast.
+CREATE INDEX vehicles_position_geography_idx
+	ON vehicle_server.vehicles
+	USING GIST ((position::geography));
@@ -0,0 +1 @@
+ALTER TABLE vehicle_server.vehicles DROP CONSTRAINT vehicles_shortcode_key;
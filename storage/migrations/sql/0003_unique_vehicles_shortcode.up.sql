@@ -0,0 +1,24 @@
+-- Riders unlock the vehicles with their shortcode, it must identify a single one.
+-- The databases created before it was enforced may have vehicles sharing a shortcode,
+-- which must be renamed by hand first: abort with the list of them, rather than guessing.
+DO $$
+DECLARE
+	duplicates TEXT;
+BEGIN
+	SELECT string_agg(format('%L (vehicles %s)', shortcode, ids), ', ' ORDER BY shortcode) INTO duplicates
+	FROM (
+		SELECT shortcode, string_agg(id::TEXT, ', ' ORDER BY id) AS ids
+		FROM vehicle_server.vehicles
+		GROUP BY shortcode
+		HAVING count(*) > 1
+	) AS duplicated;
+
+	IF duplicates IS NOT NULL THEN
+		RAISE EXCEPTION 'shortcodes used by several vehicles: %', duplicates
+			USING HINT = 'Give these vehicles distinct shortcodes, then run the migrations again.';
+	END IF;
+END
+$$;
+
+ALTER TABLE vehicle_server.vehicles
+	ADD CONSTRAINT vehicles_shortcode_key UNIQUE (shortcode);
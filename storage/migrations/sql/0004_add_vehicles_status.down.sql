@@ -0,0 +1 @@
+ALTER TABLE vehicle_server.vehicles DROP COLUMN status;
@@ -0,0 +1,4 @@
+-- The transitions between the statuses are enforced by the application.
+ALTER TABLE vehicle_server.vehicles
+	ADD COLUMN status TEXT NOT NULL DEFAULT 'available'
+	CONSTRAINT vehicles_status_check CHECK (status IN ('available', 'reserved', 'in_use', 'maintenance', 'retired'));
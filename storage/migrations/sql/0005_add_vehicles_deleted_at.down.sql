@@ -0,0 +1,2 @@
+DELETE FROM vehicle_server.vehicles WHERE deleted_at IS NOT NULL;
+ALTER TABLE vehicle_server.vehicles DROP COLUMN deleted_at;
@@ -0,0 +1,2 @@
+-- Deleted vehicles are kept until they are purged.
+ALTER TABLE vehicle_server.vehicles ADD COLUMN deleted_at TIMESTAMPTZ;
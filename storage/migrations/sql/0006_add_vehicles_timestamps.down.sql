@@ -0,0 +1,2 @@
+DROP INDEX vehicle_server.vehicles_updated_at_idx;
+ALTER TABLE vehicle_server.vehicles DROP COLUMN created_at, DROP COLUMN updated_at;
@@ -0,0 +1,8 @@
+-- The existing vehicles are considered created and updated when migrating.
+ALTER TABLE vehicle_server.vehicles
+	ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- The sync jobs list the vehicles updated since their previous run.
+CREATE INDEX vehicles_updated_at_idx
+	ON vehicle_server.vehicles (updated_at);
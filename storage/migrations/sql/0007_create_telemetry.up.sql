@@ -0,0 +1,11 @@
+-- The telemetry is append-only, the purged vehicles take their history with them.
+CREATE TABLE vehicle_server.telemetry (
+	id BIGSERIAL PRIMARY KEY,
+	vehicle_id INTEGER NOT NULL REFERENCES vehicle_server.vehicles (id) ON DELETE CASCADE,
+	recorded_at TIMESTAMPTZ NOT NULL,
+	battery SMALLINT NOT NULL,
+	position GEOMETRY(POINT, 4326) NOT NULL
+);
+
+CREATE INDEX telemetry_vehicle_id_recorded_at_idx
+	ON vehicle_server.telemetry (vehicle_id, recorded_at);
@@ -0,0 +1 @@
+ALTER TABLE vehicle_server.vehicles DROP COLUMN last_reported_at;
@@ -0,0 +1,3 @@
+-- The time of the latest position report keeps the delayed ones from moving the vehicles back,
+-- it is NULL until a vehicle reports its position.
+ALTER TABLE vehicle_server.vehicles ADD COLUMN last_reported_at TIMESTAMPTZ;
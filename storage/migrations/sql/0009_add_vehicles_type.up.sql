@@ -0,0 +1,5 @@
+-- The fleet only had scooters before the types, the attributes depend on the type.
+ALTER TABLE vehicle_server.vehicles
+	ADD COLUMN type TEXT NOT NULL DEFAULT 'scooter'
+	CONSTRAINT vehicles_type_check CHECK (type IN ('scooter', 'bike', 'moped')),
+	ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,18 @@
+-- The reservations are kept once ended, the purged vehicles take theirs with them.
+CREATE TABLE vehicle_server.reservations (
+	id BIGSERIAL PRIMARY KEY,
+	vehicle_id INTEGER NOT NULL REFERENCES vehicle_server.vehicles (id) ON DELETE CASCADE,
+	rider_id TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'active'
+		CONSTRAINT reservations_status_check CHECK (status IN ('active', 'canceled', 'expired')),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at TIMESTAMPTZ NOT NULL,
+	ended_at TIMESTAMPTZ
+);
+
+-- A vehicle has at most one active reservation, even when reserved concurrently.
+CREATE UNIQUE INDEX reservations_vehicle_id_active_key
+	ON vehicle_server.reservations (vehicle_id) WHERE status = 'active';
+
+CREATE INDEX reservations_expires_at_active_idx
+	ON vehicle_server.reservations (expires_at) WHERE status = 'active';
@@ -0,0 +1,7 @@
+DROP TABLE vehicle_server.trips;
+
+UPDATE vehicle_server.reservations SET status = 'canceled' WHERE status = 'fulfilled';
+
+ALTER TABLE vehicle_server.reservations
+	DROP CONSTRAINT reservations_status_check,
+	ADD CONSTRAINT reservations_status_check CHECK (status IN ('active', 'canceled', 'expired'));
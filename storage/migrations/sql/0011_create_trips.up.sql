@@ -0,0 +1,28 @@
+-- The reservations used to start a trip are fulfilled.
+ALTER TABLE vehicle_server.reservations
+	DROP CONSTRAINT reservations_status_check,
+	ADD CONSTRAINT reservations_status_check CHECK (status IN ('active', 'canceled', 'expired', 'fulfilled'));
+
+-- The end of a trip is NULL while it is ongoing, the purged vehicles take their trips with them.
+CREATE TABLE vehicle_server.trips (
+	id BIGSERIAL PRIMARY KEY,
+	vehicle_id INTEGER NOT NULL REFERENCES vehicle_server.vehicles (id) ON DELETE CASCADE,
+	rider_id TEXT NOT NULL,
+	start_position GEOMETRY(POINT, 4326) NOT NULL,
+	start_battery SMALLINT NOT NULL,
+	started_at TIMESTAMPTZ NOT NULL,
+	end_position GEOMETRY(POINT, 4326),
+	end_battery SMALLINT,
+	ended_at TIMESTAMPTZ,
+	distance DOUBLE PRECISION NOT NULL DEFAULT 0
+);
+
+-- A vehicle is in at most one ongoing trip, even when started concurrently.
+CREATE UNIQUE INDEX trips_vehicle_id_ongoing_key
+	ON vehicle_server.trips (vehicle_id) WHERE ended_at IS NULL;
+
+CREATE INDEX trips_vehicle_id_started_at_idx
+	ON vehicle_server.trips (vehicle_id, started_at);
+
+CREATE INDEX trips_rider_id_started_at_idx
+	ON vehicle_server.trips (rider_id, started_at);
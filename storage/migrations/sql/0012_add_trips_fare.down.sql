@@ -0,0 +1 @@
+ALTER TABLE vehicle_server.trips DROP COLUMN fare;
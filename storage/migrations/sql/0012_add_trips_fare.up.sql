@@ -0,0 +1,2 @@
+-- The itemized fare of a trip is recorded when it ends, it stays NULL for the trips ended before.
+ALTER TABLE vehicle_server.trips ADD COLUMN fare JSONB;
@@ -4,33 +4,70 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/Cirederf1/vehicle-server/storage/migrations"
+	"github.com/Cirederf1/vehicle-server/storage/reservationstore"
+	"github.com/Cirederf1/vehicle-server/storage/telemetrystore"
+	"github.com/Cirederf1/vehicle-server/storage/tripstore"
 	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
-const createDBStatement = `
-CREATE EXTENSION IF NOT EXISTS postgis;
-CREATE SCHEMA IF NOT EXISTS vehicle_server;
-CREATE TABLE IF NOT EXISTS vehicle_server.vehicles (
-	id SERIAL PRIMARY KEY,
-	shortcode TEXT NOT NULL,
-	battery SMALLINT,
-	position GEOMETRY(POINT, 4326) not null
-);
-`
+// poolStatsInterval is how often the connection pool statistics are logged.
+const poolStatsInterval = time.Minute
+
+// PGXConfig configures the PostgreSQL storage.
+// The zero value of the pool settings keeps the pgxpool defaults.
+type PGXConfig struct {
+	DatabaseURL string
+
+	// MaxConns is the maximum size of the connection pool.
+	MaxConns int32
+	// MaxConnIdleTime is the duration after which an idle connection is closed.
+	MaxConnIdleTime time.Duration
+	// MaxConnLifetime is the duration after which a connection is closed.
+	MaxConnLifetime time.Duration
+
+	// SkipMigrations disables applying the pending migrations on startup.
+	SkipMigrations bool
+}
 
 type PGXStore struct {
-	conn *pgx.Conn
+	pool     *pgxpool.Pool
+	migrator *migrations.Migrator
+	logger   *zap.Logger
+
+	stopStats chan struct{}
+	statsDone chan struct{}
+	// closeOnce makes Close safe to call more than once.
+	closeOnce sync.Once
 }
 
-func NewPGXStore(ctx context.Context, databaseURL string, logger *zap.Logger) (*PGXStore, error) {
-	var (
-		conn *pgx.Conn
-		err  error
-	)
+func NewPGXStore(ctx context.Context, cfg PGXConfig, logger *zap.Logger) (*PGXStore, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the database url: %w", err)
+	}
+
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+
+	// The pool connects lazily, pinging forces a first connection.
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the connection pool: %w", err)
+	}
 
 	err = retry(
 		ctx,
@@ -39,8 +76,7 @@ func NewPGXStore(ctx context.Context, databaseURL string, logger *zap.Logger) (*
 		func() error {
 			logger.Info("Attempting to connect to the database")
 
-			conn, err = pgx.Connect(ctx, databaseURL)
-			if err != nil {
+			if err := pool.Ping(ctx); err != nil {
 				return fmt.Errorf("could not connect to the database: %w", err)
 			}
 
@@ -48,35 +84,146 @@ func NewPGXStore(ctx context.Context, databaseURL string, logger *zap.Logger) (*
 		},
 	)
 	if err != nil {
+		pool.Close()
 		return nil, err
 	}
 
-	if err := conn.Ping(ctx); err != nil {
-		return nil, fmt.Errorf("could not ping the database: %w", err)
+	migrator, err := migrations.NewMigrator(pool, logger)
+	if err != nil {
+		pool.Close()
+		return nil, err
 	}
 
-	// Create the database. We should run migrations here,
-	// but this is a toy project :-).
-	if _, err := conn.Exec(ctx, createDBStatement); err != nil {
-		return nil, fmt.Errorf("could not create the database: %w", err)
+	if !cfg.SkipMigrations {
+		if err := migrator.Up(ctx); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("could not migrate the database: %w", err)
+		}
 	}
 
-	return &PGXStore{conn: conn}, nil
+	logger.Info(
+		"Connected to the database",
+		zap.Int32("pool-max-conns", poolConfig.MaxConns),
+		zap.Duration("pool-max-conn-idle-time", poolConfig.MaxConnIdleTime),
+		zap.Duration("pool-max-conn-lifetime", poolConfig.MaxConnLifetime),
+	)
+
+	s := &PGXStore{
+		pool:      pool,
+		migrator:  migrator,
+		logger:    logger,
+		stopStats: make(chan struct{}),
+		statsDone: make(chan struct{}),
+	}
+
+	go s.reportStats()
+
+	return s, nil
 }
 
 func (s *PGXStore) Close() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	s.closeOnce.Do(func() {
+		close(s.stopStats)
+		<-s.statsDone
 
-	if err := s.conn.Close(ctx); err != nil {
-		return fmt.Errorf("could not close the connection to the database: %w", err)
-	}
+		s.logStats()
+		s.pool.Close()
+	})
 
 	return nil
 }
 
 func (s *PGXStore) Vehicle() vehiclestore.Store {
-	return vehiclestore.NewPGXStore(s.conn)
+	return vehiclestore.NewPGXStore(s.pool)
+}
+
+func (s *PGXStore) Telemetry() telemetrystore.Store {
+	return telemetrystore.NewPGXStore(s.pool)
+}
+
+func (s *PGXStore) Reservation() reservationstore.Store {
+	return reservationstore.NewPGXStore(s.pool)
+}
+
+func (s *PGXStore) Trip() tripstore.Store {
+	return tripstore.NewPGXStore(s.pool)
+}
+
+func (s *PGXStore) WithTx(ctx context.Context, fn func(Store) error) error {
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		return fn(&pgxTxStore{tx: tx})
+	})
+}
+
+// Migrator returns the migrator of the database schema.
+func (s *PGXStore) Migrator() *migrations.Migrator {
+	return s.migrator
+}
+
+// reportStats periodically logs the connection pool statistics until the store is closed.
+func (s *PGXStore) reportStats() {
+	defer close(s.statsDone)
+
+	ticker := time.NewTicker(poolStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopStats:
+			return
+		case <-ticker.C:
+			s.logStats()
+		}
+	}
+}
+
+func (s *PGXStore) logStats() {
+	stat := s.pool.Stat()
+
+	s.logger.Info(
+		"Database connection pool statistics",
+		zap.Int32("total-conns", stat.TotalConns()),
+		zap.Int32("acquired-conns", stat.AcquiredConns()),
+		zap.Int32("idle-conns", stat.IdleConns()),
+		zap.Int32("max-conns", stat.MaxConns()),
+		zap.Int64("acquire-count", stat.AcquireCount()),
+		zap.Duration("acquire-duration", stat.AcquireDuration()),
+		zap.Int64("empty-acquire-count", stat.EmptyAcquireCount()),
+		zap.Int64("canceled-acquire-count", stat.CanceledAcquireCount()),
+	)
+}
+
+// pgxTxStore is the Store handed to the WithTx callbacks, bound to a transaction.
+type pgxTxStore struct {
+	tx pgx.Tx
+}
+
+func (s *pgxTxStore) Vehicle() vehiclestore.Store {
+	return vehiclestore.NewPGXStore(s.tx)
+}
+
+func (s *pgxTxStore) Telemetry() telemetrystore.Store {
+	return telemetrystore.NewPGXStore(s.tx)
+}
+
+func (s *pgxTxStore) Reservation() reservationstore.Store {
+	return reservationstore.NewPGXStore(s.tx)
+}
+
+func (s *pgxTxStore) Trip() tripstore.Store {
+	return tripstore.NewPGXStore(s.tx)
+}
+
+// WithTx runs fn in a savepoint of the current transaction.
+func (s *pgxTxStore) WithTx(ctx context.Context, fn func(Store) error) error {
+	return pgx.BeginFunc(ctx, s.tx, func(tx pgx.Tx) error {
+		return fn(&pgxTxStore{tx: tx})
+	})
+}
+
+// Close is a no-op, the transaction is owned by PGXStore.WithTx.
+func (s *pgxTxStore) Close() error {
+	return nil
 }
 
 func retry(ctx context.Context, retryInterval time.Duration, maxAttempts int, do func() error) error {
@@ -0,0 +1,191 @@
+package reservationstore
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/undo"
+)
+
+// MemoryStore is a Store keeping the reservations in memory.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data map[int64]Reservation
+	idx  int64
+	// now timestamps the changes and decides of the expiry.
+	now func() time.Time
+	// dataUndo records the changes of the transactions, nil out of them.
+	dataUndo *undo.Log[int64, Reservation]
+}
+
+func NewMemoryStore() *MemoryStore {
+	return NewMemoryStoreWithClock(time.Now)
+}
+
+// NewMemoryStoreWithClock returns a MemoryStore telling the time with now.
+func NewMemoryStoreWithClock(now func() time.Time) *MemoryStore {
+	return &MemoryStore{idx: 1, data: make(map[int64]Reservation), now: now}
+}
+
+// timestamp returns the current time, without the monotonic clock reading
+// which would otherwise be compared along with the wall clock one.
+func (s *MemoryStore) timestamp() time.Time {
+	return s.now().UTC()
+}
+
+// Begin locks the store and returns a transaction changing it in place.
+// end must be called exactly once to unlock the store, and tx must not be used afterwards.
+// The changes made by tx are undone unless commit is true.
+func (s *MemoryStore) Begin() (tx *MemoryStore, end func(commit bool)) {
+	s.mu.Lock()
+
+	tx = &MemoryStore{idx: s.idx, data: s.data, now: s.now, dataUndo: undo.New[int64, Reservation]()}
+
+	return tx, func(commit bool) {
+		defer s.mu.Unlock()
+
+		tx.mu.Lock()
+		defer tx.mu.Unlock()
+
+		if !commit {
+			tx.dataUndo.Undo(s.data)
+			return
+		}
+
+		// When s is itself a transaction, rolling it back undoes the changes of tx too.
+		s.dataUndo.Merge(tx.dataUndo)
+		s.idx = tx.idx
+	}
+}
+
+// DeleteVehicles removes the reservations of the vehicles, as they are purged.
+func (s *MemoryStore) DeleteVehicles(vehicleIDs []int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := make(map[int64]struct{}, len(vehicleIDs))
+	for _, id := range vehicleIDs {
+		purged[id] = struct{}{}
+	}
+
+	for id, r := range s.data {
+		if _, ok := purged[r.VehicleID]; ok {
+			s.dataUndo.Save(s.data, id)
+			delete(s.data, id)
+		}
+	}
+}
+
+func (s *MemoryStore) Create(ctx context.Context, r Reservation, hold time.Duration) (Reservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, other := range s.data {
+		if other.VehicleID == r.VehicleID && other.Status == StatusActive {
+			return Reservation{}, ErrVehicleReserved
+		}
+	}
+
+	r.ID = s.idx
+	s.idx++
+	r.Status = StatusActive
+	r.CreatedAt = s.timestamp()
+	r.ExpiresAt = r.CreatedAt.Add(hold)
+	r.EndedAt = nil
+
+	s.dataUndo.Save(s.data, r.ID)
+	s.data[r.ID] = r
+
+	return r, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id int64) (Reservation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.data[id]
+	if !ok {
+		return Reservation{}, ErrNotFound
+	}
+
+	if r.Status == StatusActive && s.due(r) {
+		r.Status = StatusExpired
+		r.EndedAt = &r.ExpiresAt
+	}
+
+	return r, nil
+}
+
+func (s *MemoryStore) Cancel(ctx context.Context, id int64) (Reservation, error) {
+	return s.end(id, StatusCanceled)
+}
+
+func (s *MemoryStore) Fulfill(ctx context.Context, id int64) (Reservation, error) {
+	return s.end(id, StatusFulfilled)
+}
+
+// end ends an active reservation with the status.
+func (s *MemoryStore) end(id int64, status Status) (Reservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.data[id]
+	if !ok {
+		return Reservation{}, ErrNotFound
+	}
+
+	if r.Status != StatusActive || s.due(r) {
+		return Reservation{}, ErrNotActive
+	}
+
+	endedAt := s.timestamp()
+	r.Status = status
+	r.EndedAt = &endedAt
+
+	s.dataUndo.Save(s.data, id)
+	s.data[id] = r
+
+	return r, nil
+}
+
+func (s *MemoryStore) Expire(ctx context.Context) ([]Reservation, error) {
+	return s.expire(func(Reservation) bool { return true })
+}
+
+func (s *MemoryStore) ExpireVehicle(ctx context.Context, vehicleID int64) ([]Reservation, error) {
+	return s.expire(func(r Reservation) bool { return r.VehicleID == vehicleID })
+}
+
+// expire ends the active reservations past their expiry which match.
+func (s *MemoryStore) expire(match func(Reservation) bool) ([]Reservation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []Reservation
+
+	for id, r := range s.data {
+		if r.Status != StatusActive || !s.due(r) || !match(r) {
+			continue
+		}
+
+		r.Status = StatusExpired
+		r.EndedAt = &r.ExpiresAt
+
+		s.dataUndo.Save(s.data, id)
+		s.data[id] = r
+		expired = append(expired, r)
+	}
+
+	slices.SortFunc(expired, func(a, b Reservation) int { return cmp.Compare(a.ID, b.ID) })
+
+	return expired, nil
+}
+
+// due tells whether the reservation reached its expiry, the caller must hold the lock.
+func (s *MemoryStore) due(r Reservation) bool {
+	return !r.ExpiresAt.After(s.timestamp())
+}
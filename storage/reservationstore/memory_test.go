@@ -0,0 +1,16 @@
+//go:build !integration
+
+package reservationstore_test
+
+import (
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/reservationstore/reservationstoretest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	reservationstoretest.Run(t, func(t *testing.T) storage.Store {
+		return storage.NewMemoryStore()
+	})
+}
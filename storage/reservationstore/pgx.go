@@ -0,0 +1,185 @@
+package reservationstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pkgpgx "github.com/Cirederf1/vehicle-server/pkg/pgx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type PGXStore struct {
+	conn pkgpgx.DB
+}
+
+func NewPGXStore(conn pkgpgx.DB) *PGXStore {
+	return &PGXStore{conn: conn}
+}
+
+// reservationColumns are the columns read by scanReservation, as stored.
+const reservationColumns = `id, vehicle_id, rider_id, status, created_at, expires_at, ended_at`
+
+// dueCondition matches the active reservations past their expiry.
+const dueCondition = `status = 'active' AND expires_at <= now()`
+
+// lookupColumns are the reservationColumns, with the reservations past their expiry reported as expired.
+const lookupColumns = `id, vehicle_id, rider_id,
+CASE WHEN ` + dueCondition + ` THEN 'expired' ELSE status END,
+created_at, expires_at,
+CASE WHEN ` + dueCondition + ` THEN expires_at ELSE ended_at END`
+
+const createStatement = `
+INSERT INTO vehicle_server.reservations (vehicle_id, rider_id, expires_at)
+VALUES (@vehicle_id, @rider_id, now() + @hold::interval)
+RETURNING ` + reservationColumns + `;
+`
+
+func (p *PGXStore) Create(ctx context.Context, r Reservation, hold time.Duration) (Reservation, error) {
+	created, err := scanReservation(p.conn.QueryRow(
+		ctx,
+		createStatement,
+		pgx.NamedArgs{"vehicle_id": r.VehicleID, "rider_id": r.RiderID, "hold": hold},
+	))
+	if isActiveReservationViolation(err) {
+		return Reservation{}, ErrVehicleReserved
+	}
+
+	return created, err
+}
+
+const getStatement = `
+SELECT ` + lookupColumns + `
+FROM vehicle_server.reservations
+WHERE id = $1;
+`
+
+func (p *PGXStore) Get(ctx context.Context, id int64) (Reservation, error) {
+	r, err := scanReservation(p.conn.QueryRow(ctx, getStatement, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Reservation{}, ErrNotFound
+	}
+
+	return r, err
+}
+
+const endStatement = `
+UPDATE vehicle_server.reservations
+SET status = @status, ended_at = now()
+WHERE id = @id AND status = 'active' AND expires_at > now()
+RETURNING ` + reservationColumns + `;
+`
+
+func (p *PGXStore) Cancel(ctx context.Context, id int64) (Reservation, error) {
+	return p.end(ctx, id, StatusCanceled)
+}
+
+func (p *PGXStore) Fulfill(ctx context.Context, id int64) (Reservation, error) {
+	return p.end(ctx, id, StatusFulfilled)
+}
+
+// end ends an active reservation with the status.
+func (p *PGXStore) end(ctx context.Context, id int64, status Status) (Reservation, error) {
+	r, err := scanReservation(p.conn.QueryRow(ctx, endStatement, pgx.NamedArgs{"id": id, "status": string(status)}))
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return r, err
+	}
+
+	// Either the reservation does not exist, or it already ended.
+	if _, err := p.Get(ctx, id); err != nil {
+		return Reservation{}, err
+	}
+
+	return Reservation{}, ErrNotActive
+}
+
+// The expired reservations end when they expired, not when they are noticed.
+// A NULL vehicle_id expires the reservations of every vehicle.
+const expireStatement = `
+WITH expired AS (
+	UPDATE vehicle_server.reservations
+	SET status = 'expired', ended_at = expires_at
+	WHERE ` + dueCondition + ` AND (@vehicle_id::BIGINT IS NULL OR vehicle_id = @vehicle_id)
+	RETURNING ` + reservationColumns + `
+)
+SELECT ` + reservationColumns + `
+FROM expired
+ORDER BY id;
+`
+
+func (p *PGXStore) Expire(ctx context.Context) ([]Reservation, error) {
+	return p.expire(ctx, nil)
+}
+
+func (p *PGXStore) ExpireVehicle(ctx context.Context, vehicleID int64) ([]Reservation, error) {
+	return p.expire(ctx, &vehicleID)
+}
+
+// expire ends the active reservations past their expiry of a vehicle, or of every vehicle when it is nil.
+func (p *PGXStore) expire(ctx context.Context, vehicleID *int64) ([]Reservation, error) {
+	rows, err := p.conn.Query(ctx, expireStatement, pgx.NamedArgs{"vehicle_id": vehicleID})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expired []Reservation
+
+	for rows.Next() {
+		r, err := scanReservation(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		expired = append(expired, r)
+	}
+
+	return expired, rows.Err()
+}
+
+// activeReservationConstraint is the unique index on the active reservations, see the 0010 migration.
+const activeReservationConstraint = "reservations_vehicle_id_active_key"
+
+// uniqueViolation is the SQLSTATE of the unique constraint violations.
+const uniqueViolation = "23505"
+
+func isActiveReservationViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) &&
+		pgErr.Code == uniqueViolation &&
+		pgErr.ConstraintName == activeReservationConstraint
+}
+
+// scanReservation reads a reservation from a row selecting the reservationColumns or the lookupColumns.
+func scanReservation(row pgx.Row) (Reservation, error) {
+	var (
+		r      Reservation
+		status string
+	)
+
+	if err := row.Scan(
+		&r.ID,
+		&r.VehicleID,
+		&r.RiderID,
+		&status,
+		&r.CreatedAt,
+		&r.ExpiresAt,
+		&r.EndedAt,
+	); err != nil {
+		return Reservation{}, err
+	}
+
+	r.Status = Status(status)
+
+	// pgx reads the timestamps in the local time zone.
+	r.CreatedAt = r.CreatedAt.UTC()
+	r.ExpiresAt = r.ExpiresAt.UTC()
+	if r.EndedAt != nil {
+		endedAt := r.EndedAt.UTC()
+		r.EndedAt = &endedAt
+	}
+
+	return r, nil
+}
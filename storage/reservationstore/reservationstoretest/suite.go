@@ -0,0 +1,234 @@
+// Package reservationstoretest holds the behavioural tests every
+// reservationstore.Store implementation must pass.
+package reservationstoretest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/reservationstore"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errAbort = errors.New("abort")
+
+// hold is the duration of the reservations which do not expire during the tests.
+const hold = 10 * time.Minute
+
+// Run runs the behavioural tests against the reservations of the stores,
+// newStore must return an empty store.
+func Run(t *testing.T, newStore func(t *testing.T) storage.Store) {
+	t.Helper()
+
+	t.Run("creates reservations", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		vehicleID := createVehicle(t, store, "aaa")
+
+		created, err := store.Reservation().Create(ctx, reservationstore.Reservation{VehicleID: vehicleID, RiderID: "rider"}, hold)
+		require.NoError(t, err)
+		assert.NotZero(t, created.ID)
+		assert.Equal(t, vehicleID, created.VehicleID)
+		assert.Equal(t, "rider", created.RiderID)
+		assert.Equal(t, reservationstore.StatusActive, created.Status)
+		assert.Equal(t, created.CreatedAt.Add(hold), created.ExpiresAt)
+		assert.Nil(t, created.EndedAt)
+
+		got, err := store.Reservation().Get(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created, got)
+
+		_, err = store.Reservation().Get(ctx, created.ID+100)
+		assert.ErrorIs(t, err, reservationstore.ErrNotFound)
+	})
+
+	t.Run("prevents concurrent reservations of a vehicle", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		vehicleID := createVehicle(t, store, "aaa")
+		otherID := createVehicle(t, store, "bbb")
+
+		var (
+			wg   sync.WaitGroup
+			errs = make([]error, 10)
+		)
+
+		for i := range errs {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_, errs[i] = store.Reservation().Create(ctx, reservationstore.Reservation{VehicleID: vehicleID, RiderID: "rider"}, hold)
+			}()
+		}
+
+		wg.Wait()
+
+		var reserved int
+		for _, err := range errs {
+			if err == nil {
+				reserved++
+				continue
+			}
+
+			assert.ErrorIs(t, err, reservationstore.ErrVehicleReserved)
+		}
+		assert.Equal(t, 1, reserved)
+
+		// The other vehicles can still be reserved.
+		_, err := store.Reservation().Create(ctx, reservationstore.Reservation{VehicleID: otherID, RiderID: "rider"}, hold)
+		assert.NoError(t, err)
+	})
+
+	t.Run("cancels reservations", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		vehicleID := createVehicle(t, store, "aaa")
+
+		created, err := store.Reservation().Create(ctx, reservationstore.Reservation{VehicleID: vehicleID, RiderID: "rider"}, hold)
+		require.NoError(t, err)
+
+		canceled, err := store.Reservation().Cancel(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, reservationstore.StatusCanceled, canceled.Status)
+		require.NotNil(t, canceled.EndedAt)
+		assert.False(t, canceled.EndedAt.Before(created.CreatedAt))
+
+		got, err := store.Reservation().Get(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, canceled, got)
+
+		_, err = store.Reservation().Cancel(ctx, created.ID)
+		assert.ErrorIs(t, err, reservationstore.ErrNotActive)
+
+		_, err = store.Reservation().Cancel(ctx, created.ID+100)
+		assert.ErrorIs(t, err, reservationstore.ErrNotFound)
+
+		// The vehicle can be reserved again.
+		_, err = store.Reservation().Create(ctx, reservationstore.Reservation{VehicleID: vehicleID, RiderID: "other"}, hold)
+		assert.NoError(t, err)
+	})
+
+	t.Run("fulfills reservations", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		vehicleID := createVehicle(t, store, "aaa")
+
+		created, err := store.Reservation().Create(ctx, reservationstore.Reservation{VehicleID: vehicleID, RiderID: "rider"}, hold)
+		require.NoError(t, err)
+
+		fulfilled, err := store.Reservation().Fulfill(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, reservationstore.StatusFulfilled, fulfilled.Status)
+		assert.NotNil(t, fulfilled.EndedAt)
+
+		_, err = store.Reservation().Cancel(ctx, created.ID)
+		assert.ErrorIs(t, err, reservationstore.ErrNotActive)
+
+		_, err = store.Reservation().Fulfill(ctx, created.ID)
+		assert.ErrorIs(t, err, reservationstore.ErrNotActive)
+
+		_, err = store.Reservation().Fulfill(ctx, created.ID+100)
+		assert.ErrorIs(t, err, reservationstore.ErrNotFound)
+	})
+
+	t.Run("expires reservations", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		vehicleID := createVehicle(t, store, "aaa")
+		otherID := createVehicle(t, store, "bbb")
+
+		// A reservation without hold is already expired.
+		due, err := store.Reservation().Create(ctx, reservationstore.Reservation{VehicleID: vehicleID, RiderID: "rider"}, 0)
+		require.NoError(t, err)
+
+		active, err := store.Reservation().Create(ctx, reservationstore.Reservation{VehicleID: otherID, RiderID: "rider"}, hold)
+		require.NoError(t, err)
+
+		got, err := store.Reservation().Get(ctx, due.ID)
+		require.NoError(t, err)
+		assert.Equal(t, reservationstore.StatusExpired, got.Status)
+		assert.Equal(t, &due.ExpiresAt, got.EndedAt)
+
+		_, err = store.Reservation().Cancel(ctx, due.ID)
+		assert.ErrorIs(t, err, reservationstore.ErrNotActive)
+
+		// The vehicle is held until the reservation is expired.
+		_, err = store.Reservation().Create(ctx, reservationstore.Reservation{VehicleID: vehicleID, RiderID: "other"}, hold)
+		assert.ErrorIs(t, err, reservationstore.ErrVehicleReserved)
+
+		// Only the reservations of the vehicle expire.
+		expired, err := store.Reservation().ExpireVehicle(ctx, otherID)
+		require.NoError(t, err)
+		assert.Empty(t, expired)
+
+		expired, err = store.Reservation().ExpireVehicle(ctx, vehicleID)
+		require.NoError(t, err)
+		assert.Equal(t, []reservationstore.Reservation{got}, expired)
+
+		expired, err = store.Reservation().Expire(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, expired)
+
+		got, err = store.Reservation().Get(ctx, active.ID)
+		require.NoError(t, err)
+		assert.Equal(t, active, got)
+
+		_, err = store.Reservation().Create(ctx, reservationstore.Reservation{VehicleID: vehicleID, RiderID: "other"}, hold)
+		assert.NoError(t, err)
+	})
+
+	t.Run("rolls back the reservations", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		vehicleID := createVehicle(t, store, "aaa")
+
+		err := store.WithTx(ctx, func(tx storage.Store) error {
+			_, err := tx.Reservation().Create(ctx, reservationstore.Reservation{VehicleID: vehicleID, RiderID: "rider"}, hold)
+			require.NoError(t, err)
+
+			return errAbort
+		})
+		require.ErrorIs(t, err, errAbort)
+
+		_, err = store.Reservation().Create(ctx, reservationstore.Reservation{VehicleID: vehicleID, RiderID: "rider"}, hold)
+		assert.NoError(t, err)
+	})
+}
+
+func createVehicle(t *testing.T, store storage.Store, shortCode string) int64 {
+	t.Helper()
+
+	v, err := store.Vehicle().Create(
+		context.Background(),
+		vehiclestore.Vehicle{ShortCode: shortCode, BatteryLevel: 100},
+	)
+	require.NoError(t, err)
+
+	return v.ID
+}
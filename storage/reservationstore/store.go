@@ -0,0 +1,66 @@
+package reservationstore
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when no reservation has the requested id.
+	ErrNotFound = errors.New("reservation not found")
+	// ErrVehicleReserved is returned when the vehicle already has an active reservation.
+	ErrVehicleReserved = errors.New("vehicle already reserved")
+	// ErrNotActive is returned when ending a reservation which already ended.
+	ErrNotActive = errors.New("reservation not active")
+)
+
+// Status is the lifecycle state of a reservation.
+type Status string
+
+const (
+	// StatusActive reservations hold their vehicle until they expire.
+	StatusActive   Status = "active"
+	StatusCanceled Status = "canceled"
+	StatusExpired  Status = "expired"
+	// StatusFulfilled reservations ended with the start of a trip.
+	StatusFulfilled Status = "fulfilled"
+)
+
+// Reservation holds a vehicle for a rider for a few minutes.
+type Reservation struct {
+	ID        int64
+	VehicleID int64
+	RiderID   string
+	Status    Status
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// EndedAt is when the reservation was canceled or expired, nil while active.
+	EndedAt *time.Time
+}
+
+// Store keeps the reservations. A reservation past its expiry is reported as expired by the lookups,
+// but it keeps holding its vehicle until Expire ends it.
+type Store interface {
+	// Create reserves a vehicle for the hold duration, it sets the ID, status and times of the reservation.
+	// It returns ErrVehicleReserved if the vehicle has an active reservation, including one
+	// created concurrently or past its expiry but not ended by Expire yet.
+	Create(ctx context.Context, r Reservation, hold time.Duration) (Reservation, error)
+
+	// Get returns ErrNotFound if the id does not exist.
+	Get(ctx context.Context, id int64) (Reservation, error)
+
+	// Cancel ends an active reservation.
+	// It returns ErrNotFound if the id does not exist, and ErrNotActive if the reservation already ended or expired.
+	Cancel(ctx context.Context, id int64) (Reservation, error)
+
+	// Fulfill ends an active reservation as its rider starts a trip with the vehicle.
+	// It returns the same errors as Cancel.
+	Fulfill(ctx context.Context, id int64) (Reservation, error)
+
+	// Expire ends the active reservations past their expiry and returns them.
+	Expire(ctx context.Context) ([]Reservation, error)
+
+	// ExpireVehicle is Expire, for the reservations of a single vehicle.
+	ExpireVehicle(ctx context.Context, vehicleID int64) ([]Reservation, error)
+}
@@ -1,9 +1,25 @@
 package storage
 
 import (
+	"context"
+
+	"github.com/Cirederf1/vehicle-server/storage/reservationstore"
+	"github.com/Cirederf1/vehicle-server/storage/telemetrystore"
+	"github.com/Cirederf1/vehicle-server/storage/tripstore"
 	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
 )
 
 type Store interface {
 	Vehicle() vehiclestore.Store
+	Telemetry() telemetrystore.Store
+	Reservation() reservationstore.Store
+	Trip() tripstore.Store
+
+	// WithTx runs fn with a store whose operations all happen in a single transaction.
+	// The transaction is committed if fn returns nil, and rolled back otherwise.
+	// fn must only use the store it is given, not the one WithTx was called on.
+	WithTx(ctx context.Context, fn func(Store) error) error
+
+	// Close releases the resources held by the store.
+	Close() error
 }
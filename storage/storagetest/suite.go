@@ -0,0 +1,220 @@
+// Package storagetest holds the behavioural tests every
+// storage.Store implementation must pass.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/reservationstore"
+	"github.com/Cirederf1/vehicle-server/storage/telemetrystore"
+	"github.com/Cirederf1/vehicle-server/storage/tripstore"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errAbort = errors.New("abort")
+
+// Run runs the behavioural tests, newStore must return an empty store.
+func Run(t *testing.T, newStore func(t *testing.T) storage.Store) {
+	t.Helper()
+
+	t.Run("commits transactions", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		var created vehiclestore.Vehicle
+
+		err := store.WithTx(ctx, func(tx storage.Store) error {
+			var err error
+
+			created, err = tx.Vehicle().Create(ctx, newVehicle("aaa"))
+			return err
+		})
+		require.NoError(t, err)
+
+		got, err := store.Vehicle().Get(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created, got)
+	})
+
+	t.Run("rolls back transactions", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		var created vehiclestore.Vehicle
+
+		err := store.WithTx(ctx, func(tx storage.Store) error {
+			var err error
+
+			created, err = tx.Vehicle().Create(ctx, newVehicle("aaa"))
+			require.NoError(t, err)
+
+			// The transaction sees its own writes.
+			_, err = tx.Vehicle().Get(ctx, created.ID)
+			require.NoError(t, err)
+
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		_, err = store.Vehicle().Get(ctx, created.ID)
+		assert.ErrorIs(t, err, vehiclestore.ErrNotFound)
+	})
+
+	t.Run("rolls back nested transactions only", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		var outer, inner vehiclestore.Vehicle
+
+		err := store.WithTx(ctx, func(tx storage.Store) error {
+			var err error
+
+			outer, err = tx.Vehicle().Create(ctx, newVehicle("aaa"))
+			require.NoError(t, err)
+
+			err = tx.WithTx(ctx, func(tx storage.Store) error {
+				inner, err = tx.Vehicle().Create(ctx, newVehicle("bbb"))
+				require.NoError(t, err)
+
+				return errAbort
+			})
+			assert.ErrorIs(t, err, errAbort)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		_, err = store.Vehicle().Get(ctx, outer.ID)
+		assert.NoError(t, err)
+
+		_, err = store.Vehicle().Get(ctx, inner.ID)
+		assert.ErrorIs(t, err, vehiclestore.ErrNotFound)
+	})
+
+	t.Run("rolls back the nested transactions along with the enclosing one", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		kept, err := store.Vehicle().Create(ctx, newVehicle("aaa"))
+		require.NoError(t, err)
+
+		deleted, err := store.Vehicle().Create(ctx, newVehicle("bbb"))
+		require.NoError(t, err)
+
+		var created vehiclestore.Vehicle
+
+		err = store.WithTx(ctx, func(tx storage.Store) error {
+			err := tx.WithTx(ctx, func(tx storage.Store) error {
+				var err error
+
+				created, err = tx.Vehicle().Create(ctx, newVehicle("ccc"))
+				if err != nil {
+					return err
+				}
+
+				if _, err := tx.Vehicle().Transition(ctx, kept.ID, vehiclestore.StatusMaintenance); err != nil {
+					return err
+				}
+
+				_, err = tx.Vehicle().Delete(ctx, deleted.ID)
+				return err
+			})
+			require.NoError(t, err)
+
+			return errAbort
+		})
+		require.ErrorIs(t, err, errAbort)
+
+		_, err = store.Vehicle().Get(ctx, created.ID)
+		assert.ErrorIs(t, err, vehiclestore.ErrNotFound)
+
+		got, err := store.Vehicle().Get(ctx, kept.ID)
+		require.NoError(t, err)
+		assert.Equal(t, kept, got)
+
+		got, err = store.Vehicle().Get(ctx, deleted.ID)
+		require.NoError(t, err)
+		assert.Equal(t, deleted, got)
+	})
+
+	t.Run("purges the records of the purged vehicles", func(t *testing.T) {
+		var (
+			ctx        = context.Background()
+			store      = newStore(t)
+			recordedAt = time.Now().UTC().Truncate(time.Microsecond)
+		)
+
+		purged, err := store.Vehicle().Create(ctx, newVehicle("aaa"))
+		require.NoError(t, err)
+
+		kept, err := store.Vehicle().Create(ctx, newVehicle("bbb"))
+		require.NoError(t, err)
+
+		var reservations, trips [2]int64
+
+		for i, v := range []vehiclestore.Vehicle{purged, kept} {
+			require.NoError(t, store.Telemetry().Append(ctx, telemetrystore.Record{
+				VehicleID:    v.ID,
+				Position:     v.Position,
+				BatteryLevel: v.BatteryLevel,
+				RecordedAt:   recordedAt,
+			}))
+
+			reservation, err := store.Reservation().Create(ctx, reservationstore.Reservation{VehicleID: v.ID, RiderID: "rider"}, time.Hour)
+			require.NoError(t, err)
+			reservations[i] = reservation.ID
+
+			trip, err := store.Trip().Start(ctx, tripstore.Trip{
+				VehicleID: v.ID,
+				RiderID:   "rider",
+				Start:     tripstore.Endpoint{Position: v.Position, BatteryLevel: v.BatteryLevel, At: recordedAt},
+			})
+			require.NoError(t, err)
+			trips[i] = trip.ID
+		}
+
+		deleted, err := store.Vehicle().Delete(ctx, purged.ID)
+		require.NoError(t, err)
+		require.True(t, deleted)
+
+		count, err := store.Vehicle().Purge(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+
+		for i, v := range []vehiclestore.Vehicle{purged, kept} {
+			wantGone := v.ID == purged.ID
+
+			history, err := store.Telemetry().History(ctx, v.ID, recordedAt.Add(-time.Hour), recordedAt.Add(time.Hour), 0)
+			require.NoError(t, err)
+			assert.Equal(t, wantGone, len(history) == 0)
+
+			_, err = store.Reservation().Get(ctx, reservations[i])
+			assert.Equal(t, wantGone, errors.Is(err, reservationstore.ErrNotFound))
+
+			_, err = store.Trip().Get(ctx, trips[i])
+			assert.Equal(t, wantGone, errors.Is(err, tripstore.ErrNotFound))
+		}
+	})
+}
+
+func newVehicle(shortCode string) vehiclestore.Vehicle {
+	return vehiclestore.Vehicle{
+		ShortCode:    shortCode,
+		BatteryLevel: 50,
+		Position:     vehiclestore.Point{Latitude: 50, Longitude: 50},
+	}
+}
@@ -0,0 +1,134 @@
+package telemetrystore
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/undo"
+)
+
+// MemoryStore is a Store keeping the records in memory.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	mu sync.RWMutex
+	// records holds the records of each vehicle, oldest first.
+	records map[int64][]Record
+	// recordsUndo records the changes of the transactions, nil out of them.
+	recordsUndo *undo.Log[int64, []Record]
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[int64][]Record)}
+}
+
+// Begin locks the store and returns a transaction changing it in place.
+// end must be called exactly once to unlock the store, and tx must not be used afterwards.
+// The changes made by tx are undone unless commit is true.
+func (s *MemoryStore) Begin() (tx *MemoryStore, end func(commit bool)) {
+	s.mu.Lock()
+
+	tx = &MemoryStore{records: s.records, recordsUndo: undo.New[int64, []Record]()}
+
+	return tx, func(commit bool) {
+		defer s.mu.Unlock()
+
+		tx.mu.Lock()
+		defer tx.mu.Unlock()
+
+		if !commit {
+			tx.recordsUndo.Undo(s.records)
+			return
+		}
+
+		// When s is itself a transaction, rolling it back undoes the changes of tx too.
+		s.recordsUndo.Merge(tx.recordsUndo)
+	}
+}
+
+// DeleteVehicles removes the records of the vehicles, as they are purged.
+func (s *MemoryStore) DeleteVehicles(vehicleIDs []int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range vehicleIDs {
+		s.recordsUndo.Save(s.records, id)
+		delete(s.records, id)
+	}
+}
+
+func (s *MemoryStore) Append(ctx context.Context, r Record) error {
+	return s.AppendBatch(ctx, []Record{r})
+}
+
+func (s *MemoryStore) AppendBatch(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range records {
+		s.append(r)
+	}
+
+	return nil
+}
+
+// append inserts a record in the history of its vehicle, the caller must hold the lock.
+func (s *MemoryStore) append(r Record) {
+	records := s.records[r.VehicleID]
+
+	// Records sharing a time keep their order of arrival.
+	i := len(records)
+	for i > 0 && records[i-1].RecordedAt.After(r.RecordedAt) {
+		i--
+	}
+
+	s.recordsUndo.Save(s.records, r.VehicleID)
+
+	// The undo logs keep the previous slices to restore them. Appending only writes past
+	// the records they see, but inserting would shift them.
+	if i == len(records) {
+		s.records[r.VehicleID] = append(records, r)
+		return
+	}
+
+	s.records[r.VehicleID] = slices.Insert(slices.Clip(records), i, r)
+}
+
+func (s *MemoryStore) History(ctx context.Context, vehicleID int64, from, to time.Time, limit int64) ([]Record, error) {
+	if limit < 0 {
+		return nil, errNegativeLimit
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var history []Record
+
+	for _, r := range s.records[vehicleID] {
+		if limit > 0 && int64(len(history)) == limit {
+			break
+		}
+
+		if !r.RecordedAt.Before(from) && r.RecordedAt.Before(to) {
+			history = append(history, r)
+		}
+	}
+
+	return history, nil
+}
+
+func (s *MemoryStore) At(ctx context.Context, vehicleID int64, at time.Time) (Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := s.records[vehicleID]
+
+	for i := len(records) - 1; i >= 0; i-- {
+		if !records[i].RecordedAt.After(at) {
+			return records[i], nil
+		}
+	}
+
+	return Record{}, ErrNotFound
+}
@@ -0,0 +1,16 @@
+//go:build !integration
+
+package telemetrystore_test
+
+import (
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/telemetrystore/telemetrystoretest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	telemetrystoretest.Run(t, func(t *testing.T) storage.Store {
+		return storage.NewMemoryStore()
+	})
+}
@@ -0,0 +1,139 @@
+package telemetrystore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pkgpgx "github.com/Cirederf1/vehicle-server/pkg/pgx"
+	"github.com/jackc/pgx/v5"
+)
+
+type PGXStore struct {
+	conn pkgpgx.DB
+}
+
+func NewPGXStore(conn pkgpgx.DB) *PGXStore {
+	return &PGXStore{conn: conn}
+}
+
+// recordColumns are the columns read by scanRecord.
+const recordColumns = `vehicle_id, ST_Y(position), ST_X(position), battery, recorded_at`
+
+const appendStatement = `
+INSERT INTO vehicle_server.telemetry (vehicle_id, recorded_at, battery, position)
+VALUES (@vehicle_id, @recorded_at, @battery, ST_SetSRID(ST_MakePoint(@longitude, @latitude), 4326));
+`
+
+func (p *PGXStore) Append(ctx context.Context, r Record) error {
+	_, err := p.conn.Exec(ctx, appendStatement, appendArgs(r))
+
+	return err
+}
+
+func (p *PGXStore) AppendBatch(ctx context.Context, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	var batch pgx.Batch
+
+	for _, r := range records {
+		batch.Queue(appendStatement, appendArgs(r))
+	}
+
+	return p.conn.SendBatch(ctx, &batch).Close()
+}
+
+func appendArgs(r Record) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"vehicle_id":  r.VehicleID,
+		"recorded_at": r.RecordedAt,
+		"battery":     r.BatteryLevel,
+		"longitude":   r.Position.Longitude,
+		"latitude":    r.Position.Latitude,
+	}
+}
+
+// The records sharing a time are ordered by id, which follows the order of insertion.
+const historyStatement = `
+SELECT ` + recordColumns + `
+FROM vehicle_server.telemetry
+WHERE vehicle_id = @vehicle_id AND recorded_at >= @from AND recorded_at < @to
+ORDER BY recorded_at ASC, id ASC
+LIMIT @limit;
+`
+
+func (p *PGXStore) History(ctx context.Context, vehicleID int64, from, to time.Time, limit int64) ([]Record, error) {
+	if limit < 0 {
+		return nil, errNegativeLimit
+	}
+
+	args := pgx.NamedArgs{
+		"vehicle_id": vehicleID,
+		"from":       from,
+		"to":         to,
+		// LIMIT NULL does not limit the results.
+		"limit": nil,
+	}
+
+	if limit > 0 {
+		args["limit"] = limit
+	}
+
+	rows, err := p.conn.Query(ctx, historyStatement, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []Record
+
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, r)
+	}
+
+	return history, rows.Err()
+}
+
+const atStatement = `
+SELECT ` + recordColumns + `
+FROM vehicle_server.telemetry
+WHERE vehicle_id = @vehicle_id AND recorded_at <= @at
+ORDER BY recorded_at DESC, id DESC
+LIMIT 1;
+`
+
+func (p *PGXStore) At(ctx context.Context, vehicleID int64, at time.Time) (Record, error) {
+	r, err := scanRecord(p.conn.QueryRow(ctx, atStatement, pgx.NamedArgs{"vehicle_id": vehicleID, "at": at}))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Record{}, ErrNotFound
+	}
+
+	return r, err
+}
+
+// scanRecord reads a record from a row selecting the recordColumns.
+func scanRecord(row pgx.Row) (Record, error) {
+	var r Record
+
+	if err := row.Scan(
+		&r.VehicleID,
+		&r.Position.Latitude,
+		&r.Position.Longitude,
+		&r.BatteryLevel,
+		&r.RecordedAt,
+	); err != nil {
+		return Record{}, err
+	}
+
+	// pgx reads the timestamps in the local time zone.
+	r.RecordedAt = r.RecordedAt.UTC()
+
+	return r, nil
+}
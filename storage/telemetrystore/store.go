@@ -0,0 +1,39 @@
+package telemetrystore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+)
+
+// ErrNotFound is returned when no record matches the lookup.
+var ErrNotFound = errors.New("telemetry not found")
+
+var errNegativeLimit = errors.New("limit must not be negative")
+
+// Record is the position and battery level of a vehicle at a point in time.
+type Record struct {
+	VehicleID    int64
+	Position     vehiclestore.Point
+	BatteryLevel int64
+	RecordedAt   time.Time
+}
+
+// Store keeps the history of the vehicles, records are never changed once appended.
+type Store interface {
+	// Append records the position and battery level of a vehicle.
+	Append(context.Context, Record) error
+
+	// AppendBatch records many positions and battery levels at once.
+	AppendBatch(context.Context, []Record) error
+
+	// History returns the records of a vehicle from a time included to another excluded,
+	// oldest first. It returns at most limit records, 0 means no limit.
+	History(ctx context.Context, vehicleID int64, from, to time.Time, limit int64) ([]Record, error)
+
+	// At returns the last record of a vehicle at or before a time.
+	// It returns ErrNotFound if the vehicle has no record that old.
+	At(ctx context.Context, vehicleID int64, at time.Time) (Record, error)
+}
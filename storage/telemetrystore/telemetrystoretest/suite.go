@@ -0,0 +1,166 @@
+// Package telemetrystoretest holds the behavioural tests every
+// telemetrystore.Store implementation must pass.
+package telemetrystoretest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/telemetrystore"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errAbort = errors.New("abort")
+
+// start is the time of the first records.
+var start = time.Date(2024, time.March, 1, 14, 0, 0, 0, time.UTC)
+
+// Run runs the behavioural tests against the telemetry of the stores,
+// newStore must return an empty store.
+func Run(t *testing.T, newStore func(t *testing.T) storage.Store) {
+	t.Helper()
+
+	t.Run("returns the history over a time range", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		vehicleID := createVehicle(t, store, "aaa")
+		otherID := createVehicle(t, store, "bbb")
+
+		// The records may be appended out of order.
+		second := record(vehicleID, time.Minute, 50, 50, 80)
+		first := record(vehicleID, 0, 49, 49, 90)
+		third := record(vehicleID, 2*time.Minute, 51, 51, 70)
+		sameTime := record(vehicleID, 2*time.Minute, 52, 52, 69)
+
+		require.NoError(t, store.Telemetry().Append(ctx, second))
+		require.NoError(t, store.Telemetry().AppendBatch(ctx, []telemetrystore.Record{
+			first,
+			third,
+			sameTime,
+			record(otherID, time.Minute, 10, 10, 10),
+		}))
+		require.NoError(t, store.Telemetry().AppendBatch(ctx, nil))
+
+		history, err := store.Telemetry().History(ctx, vehicleID, start, start.Add(time.Hour), 0)
+		require.NoError(t, err)
+		assert.Equal(t, []telemetrystore.Record{first, second, third, sameTime}, history)
+
+		// The start is included and the end excluded.
+		history, err = store.Telemetry().History(ctx, vehicleID, start.Add(time.Minute), start.Add(2*time.Minute), 0)
+		require.NoError(t, err)
+		assert.Equal(t, []telemetrystore.Record{second}, history)
+
+		history, err = store.Telemetry().History(ctx, vehicleID, start, start.Add(time.Hour), 2)
+		require.NoError(t, err)
+		assert.Equal(t, []telemetrystore.Record{first, second}, history)
+
+		history, err = store.Telemetry().History(ctx, vehicleID, start.Add(time.Hour), start.Add(2*time.Hour), 0)
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+
+	t.Run("returns the record at a point in time", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		vehicleID := createVehicle(t, store, "aaa")
+
+		first := record(vehicleID, 0, 49, 49, 90)
+		second := record(vehicleID, time.Minute, 50, 50, 80)
+
+		for _, r := range []telemetrystore.Record{first, second} {
+			require.NoError(t, store.Telemetry().Append(ctx, r))
+		}
+
+		got, err := store.Telemetry().At(ctx, vehicleID, start)
+		require.NoError(t, err)
+		assert.Equal(t, first, got)
+
+		got, err = store.Telemetry().At(ctx, vehicleID, start.Add(90*time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, second, got)
+
+		_, err = store.Telemetry().At(ctx, vehicleID, start.Add(-time.Second))
+		assert.ErrorIs(t, err, telemetrystore.ErrNotFound)
+
+		_, err = store.Telemetry().At(ctx, vehicleID+100, start)
+		assert.ErrorIs(t, err, telemetrystore.ErrNotFound)
+	})
+
+	t.Run("rolls back the records", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		vehicleID := createVehicle(t, store, "aaa")
+
+		err := store.WithTx(ctx, func(tx storage.Store) error {
+			require.NoError(t, tx.Telemetry().Append(ctx, record(vehicleID, 0, 49, 49, 90)))
+
+			return errAbort
+		})
+		require.ErrorIs(t, err, errAbort)
+
+		_, err = store.Telemetry().At(ctx, vehicleID, start)
+		assert.ErrorIs(t, err, telemetrystore.ErrNotFound)
+
+		// The records appended after a rolled back one replace it.
+		for _, offset := range []time.Duration{0, time.Minute} {
+			require.NoError(t, store.Telemetry().Append(ctx, record(vehicleID, offset, 49, 49, 90)))
+		}
+
+		err = store.WithTx(ctx, func(tx storage.Store) error {
+			require.NoError(t, tx.Telemetry().Append(ctx, record(vehicleID, 2*time.Minute, 50, 50, 80)))
+
+			return errAbort
+		})
+		require.ErrorIs(t, err, errAbort)
+
+		require.NoError(t, store.Telemetry().Append(ctx, record(vehicleID, 3*time.Minute, 51, 51, 70)))
+
+		history, err := store.Telemetry().History(ctx, vehicleID, start, start.Add(time.Hour), 0)
+		require.NoError(t, err)
+		assert.Equal(
+			t,
+			[]telemetrystore.Record{
+				record(vehicleID, 0, 49, 49, 90),
+				record(vehicleID, time.Minute, 49, 49, 90),
+				record(vehicleID, 3*time.Minute, 51, 51, 70),
+			},
+			history,
+		)
+	})
+}
+
+func createVehicle(t *testing.T, store storage.Store, shortCode string) int64 {
+	t.Helper()
+
+	v, err := store.Vehicle().Create(
+		context.Background(),
+		vehiclestore.Vehicle{ShortCode: shortCode, BatteryLevel: 100},
+	)
+	require.NoError(t, err)
+
+	return v.ID
+}
+
+// record returns a record of a vehicle, offset from the start of the tests.
+func record(vehicleID int64, offset time.Duration, lat, lon float64, battery int64) telemetrystore.Record {
+	return telemetrystore.Record{
+		VehicleID:    vehicleID,
+		Position:     vehiclestore.Point{Latitude: lat, Longitude: lon},
+		BatteryLevel: battery,
+		RecordedAt:   start.Add(offset),
+	}
+}
@@ -0,0 +1,154 @@
+package tripstore
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/pricing"
+	"github.com/Cirederf1/vehicle-server/pkg/undo"
+)
+
+// MemoryStore is a Store keeping the trips in memory.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data map[int64]Trip
+	idx  int64
+	// dataUndo records the changes of the transactions, nil out of them.
+	dataUndo *undo.Log[int64, Trip]
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{idx: 1, data: make(map[int64]Trip)}
+}
+
+// Begin locks the store and returns a transaction changing it in place.
+// end must be called exactly once to unlock the store, and tx must not be used afterwards.
+// The changes made by tx are undone unless commit is true.
+func (s *MemoryStore) Begin() (tx *MemoryStore, end func(commit bool)) {
+	s.mu.Lock()
+
+	tx = &MemoryStore{idx: s.idx, data: s.data, dataUndo: undo.New[int64, Trip]()}
+
+	return tx, func(commit bool) {
+		defer s.mu.Unlock()
+
+		tx.mu.Lock()
+		defer tx.mu.Unlock()
+
+		if !commit {
+			tx.dataUndo.Undo(s.data)
+			return
+		}
+
+		// When s is itself a transaction, rolling it back undoes the changes of tx too.
+		s.dataUndo.Merge(tx.dataUndo)
+		s.idx = tx.idx
+	}
+}
+
+// DeleteVehicles removes the trips of the vehicles, as they are purged.
+func (s *MemoryStore) DeleteVehicles(vehicleIDs []int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := make(map[int64]struct{}, len(vehicleIDs))
+	for _, id := range vehicleIDs {
+		purged[id] = struct{}{}
+	}
+
+	for id, t := range s.data {
+		if _, ok := purged[t.VehicleID]; ok {
+			s.dataUndo.Save(s.data, id)
+			delete(s.data, id)
+		}
+	}
+}
+
+func (s *MemoryStore) Start(ctx context.Context, t Trip) (Trip, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, other := range s.data {
+		if other.VehicleID == t.VehicleID && other.End == nil {
+			return Trip{}, ErrVehicleInTrip
+		}
+	}
+
+	t.ID = s.idx
+	s.idx++
+	t.End = nil
+	t.Distance = 0
+	t.Fare = nil
+
+	s.dataUndo.Save(s.data, t.ID)
+	s.data[t.ID] = t
+
+	return t, nil
+}
+
+func (s *MemoryStore) End(ctx context.Context, id int64, end Endpoint, distance float64, fare pricing.Fare) (Trip, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.data[id]
+	if !ok {
+		return Trip{}, ErrNotFound
+	}
+
+	if t.End != nil {
+		return Trip{}, ErrEnded
+	}
+
+	t.End = &end
+	t.Distance = distance
+	t.Fare = &fare
+
+	s.dataUndo.Save(s.data, id)
+	s.data[id] = t
+
+	return t, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id int64) (Trip, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.data[id]
+	if !ok {
+		return Trip{}, ErrNotFound
+	}
+
+	return t, nil
+}
+
+func (s *MemoryStore) History(ctx context.Context, filter Filter, from, to time.Time, limit int64) ([]Trip, error) {
+	if limit < 0 {
+		return nil, errNegativeLimit
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var history []Trip
+
+	for _, t := range s.data {
+		if filter.matches(t) && !t.Start.At.Before(from) && t.Start.At.Before(to) {
+			history = append(history, t)
+		}
+	}
+
+	// The trips starting at the same time are ordered by id, which follows the order of the starts.
+	slices.SortFunc(history, func(a, b Trip) int {
+		return cmp.Or(a.Start.At.Compare(b.Start.At), cmp.Compare(a.ID, b.ID))
+	})
+
+	if limit > 0 && int64(len(history)) > limit {
+		history = history[:limit]
+	}
+
+	return history, nil
+}
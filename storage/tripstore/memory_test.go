@@ -0,0 +1,16 @@
+//go:build !integration
+
+package tripstore_test
+
+import (
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/tripstore/tripstoretest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	tripstoretest.Run(t, func(t *testing.T) storage.Store {
+		return storage.NewMemoryStore()
+	})
+}
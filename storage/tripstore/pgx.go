@@ -0,0 +1,212 @@
+package tripstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pkgpgx "github.com/Cirederf1/vehicle-server/pkg/pgx"
+	"github.com/Cirederf1/vehicle-server/pkg/pricing"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type PGXStore struct {
+	conn pkgpgx.DB
+}
+
+func NewPGXStore(conn pkgpgx.DB) *PGXStore {
+	return &PGXStore{conn: conn}
+}
+
+// tripColumns are the columns read by scanTrip.
+const tripColumns = `id, vehicle_id, rider_id,
+ST_Y(start_position), ST_X(start_position), start_battery, started_at,
+ST_Y(end_position), ST_X(end_position), end_battery, ended_at,
+distance, fare`
+
+const startStatement = `
+INSERT INTO vehicle_server.trips (vehicle_id, rider_id, start_position, start_battery, started_at)
+VALUES (@vehicle_id, @rider_id, ST_SetSRID(ST_MakePoint(@longitude, @latitude), 4326), @battery, @at)
+RETURNING ` + tripColumns + `;
+`
+
+func (p *PGXStore) Start(ctx context.Context, t Trip) (Trip, error) {
+	started, err := scanTrip(p.conn.QueryRow(
+		ctx,
+		startStatement,
+		pgx.NamedArgs{
+			"vehicle_id": t.VehicleID,
+			"rider_id":   t.RiderID,
+			"longitude":  t.Start.Position.Longitude,
+			"latitude":   t.Start.Position.Latitude,
+			"battery":    t.Start.BatteryLevel,
+			"at":         t.Start.At,
+		},
+	))
+	if isOngoingTripViolation(err) {
+		return Trip{}, ErrVehicleInTrip
+	}
+
+	return started, err
+}
+
+const endStatement = `
+UPDATE vehicle_server.trips
+SET end_position = ST_SetSRID(ST_MakePoint(@longitude, @latitude), 4326), end_battery = @battery, ended_at = @at, distance = @distance, fare = @fare
+WHERE id = @id AND ended_at IS NULL
+RETURNING ` + tripColumns + `;
+`
+
+func (p *PGXStore) End(ctx context.Context, id int64, end Endpoint, distance float64, fare pricing.Fare) (Trip, error) {
+	t, err := scanTrip(p.conn.QueryRow(
+		ctx,
+		endStatement,
+		pgx.NamedArgs{
+			"id":        id,
+			"longitude": end.Position.Longitude,
+			"latitude":  end.Position.Latitude,
+			"battery":   end.BatteryLevel,
+			"at":        end.At,
+			"distance":  distance,
+			"fare":      fare,
+		},
+	))
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return t, err
+	}
+
+	// Either the trip does not exist, or it already ended.
+	if _, err := p.Get(ctx, id); err != nil {
+		return Trip{}, err
+	}
+
+	return Trip{}, ErrEnded
+}
+
+const getStatement = `
+SELECT ` + tripColumns + `
+FROM vehicle_server.trips
+WHERE id = $1;
+`
+
+func (p *PGXStore) Get(ctx context.Context, id int64) (Trip, error) {
+	t, err := scanTrip(p.conn.QueryRow(ctx, getStatement, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Trip{}, ErrNotFound
+	}
+
+	return t, err
+}
+
+// The trips starting at the same time are ordered by id, which follows the order of the starts.
+const historyStatement = `
+SELECT ` + tripColumns + `
+FROM vehicle_server.trips
+WHERE (@vehicle_id::bigint IS NULL OR vehicle_id = @vehicle_id)
+AND (@rider_id::text IS NULL OR rider_id = @rider_id)
+AND started_at >= @from AND started_at < @to
+ORDER BY started_at ASC, id ASC
+LIMIT @limit;
+`
+
+func (p *PGXStore) History(ctx context.Context, filter Filter, from, to time.Time, limit int64) ([]Trip, error) {
+	if limit < 0 {
+		return nil, errNegativeLimit
+	}
+
+	args := pgx.NamedArgs{
+		"vehicle_id": nil,
+		"rider_id":   nil,
+		"from":       from,
+		"to":         to,
+		// LIMIT NULL does not limit the results.
+		"limit": nil,
+	}
+
+	if filter.VehicleID != 0 {
+		args["vehicle_id"] = filter.VehicleID
+	}
+	if filter.RiderID != "" {
+		args["rider_id"] = filter.RiderID
+	}
+	if limit > 0 {
+		args["limit"] = limit
+	}
+
+	rows, err := p.conn.Query(ctx, historyStatement, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []Trip
+
+	for rows.Next() {
+		t, err := scanTrip(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, t)
+	}
+
+	return history, rows.Err()
+}
+
+// ongoingTripConstraint is the unique index on the ongoing trips, see the 0011 migration.
+const ongoingTripConstraint = "trips_vehicle_id_ongoing_key"
+
+// uniqueViolation is the SQLSTATE of the unique constraint violations.
+const uniqueViolation = "23505"
+
+func isOngoingTripViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) &&
+		pgErr.Code == uniqueViolation &&
+		pgErr.ConstraintName == ongoingTripConstraint
+}
+
+// scanTrip reads a trip from a row selecting the tripColumns.
+func scanTrip(row pgx.Row) (Trip, error) {
+	var (
+		t Trip
+		// The end columns are NULL while the trip is ongoing.
+		endLatitude, endLongitude *float64
+		endBattery                *int64
+		endedAt                   *time.Time
+	)
+
+	if err := row.Scan(
+		&t.ID,
+		&t.VehicleID,
+		&t.RiderID,
+		&t.Start.Position.Latitude,
+		&t.Start.Position.Longitude,
+		&t.Start.BatteryLevel,
+		&t.Start.At,
+		&endLatitude,
+		&endLongitude,
+		&endBattery,
+		&endedAt,
+		&t.Distance,
+		&t.Fare,
+	); err != nil {
+		return Trip{}, err
+	}
+
+	// pgx reads the timestamps in the local time zone.
+	t.Start.At = t.Start.At.UTC()
+
+	if endedAt != nil {
+		t.End = &Endpoint{
+			Position:     vehiclestore.Point{Latitude: *endLatitude, Longitude: *endLongitude},
+			BatteryLevel: *endBattery,
+			At:           endedAt.UTC(),
+		}
+	}
+
+	return t, nil
+}
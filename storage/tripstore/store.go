@@ -0,0 +1,82 @@
+package tripstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/pricing"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+)
+
+var (
+	// ErrNotFound is returned when no trip has the requested id.
+	ErrNotFound = errors.New("trip not found")
+	// ErrVehicleInTrip is returned when starting a trip with a vehicle already in an ongoing one.
+	ErrVehicleInTrip = errors.New("vehicle already in a trip")
+	// ErrEnded is returned when ending a trip which already ended.
+	ErrEnded = errors.New("trip already ended")
+)
+
+var errNegativeLimit = errors.New("limit must not be negative")
+
+// Endpoint is where a trip starts or ends, with the battery level of its vehicle at that time.
+type Endpoint struct {
+	Position     vehiclestore.Point
+	BatteryLevel int64
+	At           time.Time
+}
+
+// Trip is the ride of a vehicle by a rider.
+type Trip struct {
+	ID        int64
+	VehicleID int64
+	RiderID   string
+	Start     Endpoint
+	// End is nil while the trip is ongoing.
+	End *Endpoint
+	// Distance is the length in meters of the path travelled by the vehicle, zero while the trip is ongoing.
+	Distance float64
+	// Fare is nil while the trip is ongoing, and for the trips ended before the fares were recorded.
+	Fare *pricing.Fare
+}
+
+// BatteryConsumed returns the battery level used during the trip, zero while it is ongoing.
+// It is negative when the battery was swapped for a fuller one.
+func (t Trip) BatteryConsumed() int64 {
+	if t.End == nil {
+		return 0
+	}
+
+	return t.Start.BatteryLevel - t.End.BatteryLevel
+}
+
+// Filter selects the trips of a vehicle, of a rider, or of both.
+// The zero value of a field matches every trip.
+type Filter struct {
+	VehicleID int64
+	RiderID   string
+}
+
+func (f Filter) matches(t Trip) bool {
+	return (f.VehicleID == 0 || t.VehicleID == f.VehicleID) &&
+		(f.RiderID == "" || t.RiderID == f.RiderID)
+}
+
+// Store keeps the trips, a vehicle is in at most one ongoing trip.
+type Store interface {
+	// Start records a new ongoing trip and sets its ID.
+	// It returns ErrVehicleInTrip if the vehicle is in an ongoing trip, including one started concurrently.
+	Start(context.Context, Trip) (Trip, error)
+
+	// End records the end of an ongoing trip, the distance travelled during it and its fare.
+	// It returns ErrNotFound if the id does not exist, and ErrEnded if the trip already ended.
+	End(ctx context.Context, id int64, end Endpoint, distance float64, fare pricing.Fare) (Trip, error)
+
+	// Get returns ErrNotFound if the id does not exist.
+	Get(ctx context.Context, id int64) (Trip, error)
+
+	// History returns the trips matching the filter started from a time included to another excluded,
+	// oldest first. It returns at most limit trips, 0 means no limit.
+	History(ctx context.Context, filter Filter, from, to time.Time, limit int64) ([]Trip, error)
+}
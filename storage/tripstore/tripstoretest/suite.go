@@ -0,0 +1,231 @@
+// Package tripstoretest holds the behavioural tests every
+// tripstore.Store implementation must pass.
+package tripstoretest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/pricing"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/tripstore"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errAbort = errors.New("abort")
+
+// fare is the fare of the ended trips.
+var fare = pricing.Fare{
+	Currency: "EUR",
+	Items: []pricing.Item{
+		{Kind: pricing.ItemUnlock, Amount: 100},
+		{Kind: pricing.ItemTime, Amount: 250},
+	},
+	Total: 350,
+}
+
+// start is the time of the first trips.
+var start = time.Date(2024, time.March, 1, 14, 0, 0, 0, time.UTC)
+
+// Run runs the behavioural tests against the trips of the stores,
+// newStore must return an empty store.
+func Run(t *testing.T, newStore func(t *testing.T) storage.Store) {
+	t.Helper()
+
+	t.Run("starts and ends trips", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		vehicleID := createVehicle(t, store, "aaa")
+
+		started, err := store.Trip().Start(ctx, trip(vehicleID, "rider", 0))
+		require.NoError(t, err)
+		assert.NotZero(t, started.ID)
+		assert.Equal(t, trip(vehicleID, "rider", 0).Start, started.Start)
+		assert.Nil(t, started.End)
+		assert.Nil(t, started.Fare)
+		assert.Zero(t, started.BatteryConsumed())
+
+		got, err := store.Trip().Get(ctx, started.ID)
+		require.NoError(t, err)
+		assert.Equal(t, started, got)
+
+		end := endpoint(10*time.Minute, 50.01, 50.01, 72)
+
+		ended, err := store.Trip().End(ctx, started.ID, end, 1520.5, fare)
+		require.NoError(t, err)
+		assert.Equal(t, &end, ended.End)
+		assert.Equal(t, 1520.5, ended.Distance)
+		assert.Equal(t, &fare, ended.Fare)
+		assert.Equal(t, int64(18), ended.BatteryConsumed())
+
+		got, err = store.Trip().Get(ctx, started.ID)
+		require.NoError(t, err)
+		assert.Equal(t, ended, got)
+
+		_, err = store.Trip().End(ctx, started.ID, end, 0, fare)
+		assert.ErrorIs(t, err, tripstore.ErrEnded)
+
+		_, err = store.Trip().End(ctx, started.ID+100, end, 0, fare)
+		assert.ErrorIs(t, err, tripstore.ErrNotFound)
+
+		_, err = store.Trip().Get(ctx, started.ID+100)
+		assert.ErrorIs(t, err, tripstore.ErrNotFound)
+
+		// The vehicle can start another trip once the first one ended.
+		_, err = store.Trip().Start(ctx, trip(vehicleID, "other", time.Hour))
+		assert.NoError(t, err)
+	})
+
+	t.Run("prevents concurrent trips of a vehicle", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		vehicleID := createVehicle(t, store, "aaa")
+		otherID := createVehicle(t, store, "bbb")
+
+		var (
+			wg   sync.WaitGroup
+			errs = make([]error, 10)
+		)
+
+		for i := range errs {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_, errs[i] = store.Trip().Start(ctx, trip(vehicleID, "rider", 0))
+			}()
+		}
+
+		wg.Wait()
+
+		var started int
+		for _, err := range errs {
+			if err == nil {
+				started++
+				continue
+			}
+
+			assert.ErrorIs(t, err, tripstore.ErrVehicleInTrip)
+		}
+		assert.Equal(t, 1, started)
+
+		// The other vehicles can still start a trip.
+		_, err := store.Trip().Start(ctx, trip(otherID, "rider", 0))
+		assert.NoError(t, err)
+	})
+
+	t.Run("returns the history of the vehicles and riders", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		vehicleID := createVehicle(t, store, "aaa")
+		otherID := createVehicle(t, store, "bbb")
+
+		var trips []tripstore.Trip
+
+		for _, tr := range []tripstore.Trip{
+			trip(vehicleID, "alice", 0),
+			trip(otherID, "bob", 0),
+			trip(otherID, "alice", time.Hour),
+			trip(vehicleID, "bob", 2*time.Hour),
+		} {
+			started, err := store.Trip().Start(ctx, tr)
+			require.NoError(t, err)
+
+			ended, err := store.Trip().End(ctx, started.ID, endpoint(tr.Start.At.Sub(start)+time.Minute, 50, 50, 90), 100, fare)
+			require.NoError(t, err)
+
+			trips = append(trips, ended)
+		}
+
+		history, err := store.Trip().History(ctx, tripstore.Filter{VehicleID: vehicleID}, start, start.Add(24*time.Hour), 0)
+		require.NoError(t, err)
+		assert.Equal(t, []tripstore.Trip{trips[0], trips[3]}, history)
+
+		history, err = store.Trip().History(ctx, tripstore.Filter{RiderID: "alice"}, start, start.Add(24*time.Hour), 0)
+		require.NoError(t, err)
+		assert.Equal(t, []tripstore.Trip{trips[0], trips[2]}, history)
+
+		history, err = store.Trip().History(ctx, tripstore.Filter{VehicleID: otherID, RiderID: "bob"}, start, start.Add(24*time.Hour), 0)
+		require.NoError(t, err)
+		assert.Equal(t, []tripstore.Trip{trips[1]}, history)
+
+		// The start is included and the end excluded, the trips starting at the same time keep their order.
+		history, err = store.Trip().History(ctx, tripstore.Filter{}, start, start.Add(time.Hour), 0)
+		require.NoError(t, err)
+		assert.Equal(t, []tripstore.Trip{trips[0], trips[1]}, history)
+
+		history, err = store.Trip().History(ctx, tripstore.Filter{}, start, start.Add(24*time.Hour), 3)
+		require.NoError(t, err)
+		assert.Equal(t, trips[:3], history)
+
+		history, err = store.Trip().History(ctx, tripstore.Filter{RiderID: "carol"}, start, start.Add(24*time.Hour), 0)
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+
+	t.Run("rolls back the trips", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		vehicleID := createVehicle(t, store, "aaa")
+
+		err := store.WithTx(ctx, func(tx storage.Store) error {
+			_, err := tx.Trip().Start(ctx, trip(vehicleID, "rider", 0))
+			require.NoError(t, err)
+
+			return errAbort
+		})
+		require.ErrorIs(t, err, errAbort)
+
+		history, err := store.Trip().History(ctx, tripstore.Filter{VehicleID: vehicleID}, start, start.Add(time.Hour), 0)
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+}
+
+func createVehicle(t *testing.T, store storage.Store, shortCode string) int64 {
+	t.Helper()
+
+	v, err := store.Vehicle().Create(
+		context.Background(),
+		vehiclestore.Vehicle{ShortCode: shortCode, BatteryLevel: 100},
+	)
+	require.NoError(t, err)
+
+	return v.ID
+}
+
+// trip returns a trip of a vehicle by a rider, starting at an offset from the start of the tests.
+func trip(vehicleID int64, riderID string, offset time.Duration) tripstore.Trip {
+	return tripstore.Trip{
+		VehicleID: vehicleID,
+		RiderID:   riderID,
+		Start:     endpoint(offset, 50, 50, 90),
+	}
+}
+
+// endpoint returns an endpoint of a trip, at an offset from the start of the tests.
+func endpoint(offset time.Duration, lat, lon float64, battery int64) tripstore.Endpoint {
+	return tripstore.Endpoint{
+		Position:     vehiclestore.Point{Latitude: lat, Longitude: lon},
+		BatteryLevel: battery,
+		At:           start.Add(offset),
+	}
+}
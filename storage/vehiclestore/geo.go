@@ -0,0 +1,98 @@
+package vehiclestore
+
+import "math"
+
+// earthRadius is the mean radius of the earth in meters.
+const earthRadius = 6371008.8
+
+// distance returns the great-circle distance in meters between two points,
+// computed with the haversine formula.
+func distance(from, to Point) float64 {
+	var (
+		lat1 = radians(from.Latitude)
+		lat2 = radians(to.Latitude)
+		dLat = radians(to.Latitude - from.Latitude)
+		dLon = radians(to.Longitude - from.Longitude)
+	)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// PathLength returns the length in meters of the path going through the points in order.
+func PathLength(path []Point) float64 {
+	var length float64
+
+	for i := 1; i < len(path); i++ {
+		length += distance(path[i-1], path[i])
+	}
+
+	return length
+}
+
+// bearing returns the initial bearing in degrees, within [0, 360), of the great circle from a point to another.
+// It is false when the points are the same.
+func bearing(from, to Point) (float64, bool) {
+	if from == to {
+		return 0, false
+	}
+
+	var (
+		lat1 = radians(from.Latitude)
+		lat2 = radians(to.Latitude)
+		dLon = radians(to.Longitude - from.Longitude)
+	)
+
+	theta := math.Atan2(
+		math.Sin(dLon)*math.Cos(lat2),
+		math.Cos(lat1)*math.Sin(lat2)-math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon),
+	)
+
+	return math.Mod(degrees(theta)+360, 360), true
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+func (b BoundingBox) contains(p Point) bool {
+	return p.Latitude >= b.Min.Latitude && p.Latitude <= b.Max.Latitude &&
+		p.Longitude >= b.Min.Longitude && p.Longitude <= b.Max.Longitude
+}
+
+// contains tells whether p is inside the exterior ring and outside of the holes.
+func (p Polygon) contains(pt Point) bool {
+	if len(p) == 0 || !ringContains(p[0], pt) {
+		return false
+	}
+
+	for _, hole := range p[1:] {
+		if ringContains(hole, pt) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ringContains implements the even-odd rule by casting a ray from pt towards the east.
+func ringContains(ring []Point, pt Point) bool {
+	inside := false
+
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+
+		if (a.Latitude > pt.Latitude) != (b.Latitude > pt.Latitude) &&
+			pt.Longitude < (b.Longitude-a.Longitude)*(pt.Latitude-a.Latitude)/(b.Latitude-a.Latitude)+a.Longitude {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
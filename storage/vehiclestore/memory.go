@@ -1,32 +1,408 @@
 package vehiclestore
 
 import (
+	"cmp"
 	"context"
-	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/undo"
 )
 
+// MemoryStore is a Store keeping the vehicles in memory.
+// It is safe for concurrent use.
 type MemoryStore struct {
-	Data map[int64]Vehicle
-	idx  int64
+	mu   sync.RWMutex
+	data map[int64]Vehicle
+	// trash holds the deleted vehicles until they are purged.
+	trash map[int64]deletedVehicle
+	idx   int64
+	// now timestamps the changes.
+	now func() time.Time
+	// dataUndo and trashUndo record the changes of the transactions, nil out of them.
+	dataUndo  *undo.Log[int64, Vehicle]
+	trashUndo *undo.Log[int64, deletedVehicle]
+}
+
+type deletedVehicle struct {
+	Vehicle
+	deletedAt time.Time
 }
 
 func NewMemoryStore() *MemoryStore {
-	return &MemoryStore{idx: 1, Data: make(map[int64]Vehicle)}
+	return NewMemoryStoreWithClock(time.Now)
+}
+
+// NewMemoryStoreWithClock returns a MemoryStore timestamping the changes with now.
+func NewMemoryStoreWithClock(now func() time.Time) *MemoryStore {
+	return &MemoryStore{idx: 1, data: make(map[int64]Vehicle), trash: make(map[int64]deletedVehicle), now: now}
+}
+
+// timestamp returns the current time, without the monotonic clock reading
+// which would otherwise be compared along with the wall clock one.
+func (s *MemoryStore) timestamp() time.Time {
+	return s.now().UTC()
+}
+
+// Begin locks the store and returns a transaction changing it in place.
+// end must be called exactly once to unlock the store, and tx must not be used afterwards.
+// The changes made by tx are undone unless commit is true.
+func (s *MemoryStore) Begin() (tx *MemoryStore, end func(commit bool)) {
+	s.mu.Lock()
+
+	tx = &MemoryStore{
+		idx:       s.idx,
+		data:      s.data,
+		trash:     s.trash,
+		now:       s.now,
+		dataUndo:  undo.New[int64, Vehicle](),
+		trashUndo: undo.New[int64, deletedVehicle](),
+	}
+
+	return tx, func(commit bool) {
+		defer s.mu.Unlock()
+
+		tx.mu.Lock()
+		defer tx.mu.Unlock()
+
+		if !commit {
+			tx.dataUndo.Undo(s.data)
+			tx.trashUndo.Undo(s.trash)
+			return
+		}
+
+		// When s is itself a transaction, rolling it back undoes the changes of tx too.
+		s.dataUndo.Merge(tx.dataUndo)
+		s.trashUndo.Merge(tx.trashUndo)
+		s.idx = tx.idx
+	}
+}
+
+// setVehicle saves a vehicle, the caller must hold the lock.
+func (s *MemoryStore) setVehicle(v Vehicle) {
+	s.dataUndo.Save(s.data, v.ID)
+	s.data[v.ID] = v
 }
 
 func (s *MemoryStore) Create(ctx context.Context, v Vehicle) (Vehicle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shortCodeUsed(v.ShortCode, 0) {
+		return Vehicle{}, ErrDuplicateShortCode
+	}
+
 	v.ID = s.idx
 	s.idx++
+	v.Type = cmp.Or(v.Type, TypeScooter)
+	v.Status = StatusAvailable
+	v.CreatedAt = s.timestamp()
+	v.UpdatedAt = v.CreatedAt
+	v.LastReportedAt = nil
+	v.Distance, v.Bearing = nil, nil
+
+	s.setVehicle(v)
+
+	return v, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id int64) (Vehicle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, ok := s.data[id]
+	if !ok {
+		return Vehicle{}, ErrNotFound
+	}
+
+	return v, nil
+}
+
+func (s *MemoryStore) GetByShortCode(ctx context.Context, shortCode string) (Vehicle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, ok := s.findShortCode(shortCode)
+	if !ok {
+		return Vehicle{}, ErrNotFound
+	}
+
+	return v, nil
+}
+
+// findShortCode looks up the vehicle using a shortcode, the caller must hold the lock.
+func (s *MemoryStore) findShortCode(shortCode string) (Vehicle, bool) {
+	for _, v := range s.data {
+		if v.ShortCode == shortCode {
+			return v, true
+		}
+	}
+
+	return Vehicle{}, false
+}
+
+// shortCodeUsed tells whether a vehicle, deleted or not, uses a shortcode.
+// The caller must hold the lock.
+func (s *MemoryStore) shortCodeUsed(shortCode string, except int64) bool {
+	if v, ok := s.findShortCode(shortCode); ok && v.ID != except {
+		return true
+	}
+
+	for _, v := range s.trash {
+		if v.ShortCode == shortCode && v.ID != except {
+			return true
+		}
+	}
+
+	return false
+}
 
-	s.Data[v.ID] = v
+func (s *MemoryStore) Update(ctx context.Context, v Vehicle) (Vehicle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.data[v.ID]
+	if !ok {
+		return Vehicle{}, ErrNotFound
+	}
+
+	if s.shortCodeUsed(v.ShortCode, v.ID) {
+		return Vehicle{}, ErrDuplicateShortCode
+	}
+
+	v.Type = cmp.Or(v.Type, TypeScooter)
+	v.Status = current.Status
+	v.CreatedAt = current.CreatedAt
+	v.UpdatedAt = s.timestamp()
+	v.LastReportedAt = current.LastReportedAt
+	v.Distance, v.Bearing = nil, nil
+	s.setVehicle(v)
 
 	return v, nil
 }
 
-func (s *MemoryStore) FindClosestFrom(ctx context.Context, location Point, limit int64) ([]Vehicle, error) {
-	return nil, errors.New("not implemented")
+func (s *MemoryStore) ReportPositions(ctx context.Context, reports []PositionReport) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var missing []int64
+
+	for _, report := range reports {
+		v, ok := s.data[report.ID]
+		if !ok {
+			missing = append(missing, report.ID)
+			continue
+		}
+
+		if v.LastReportedAt != nil && !v.LastReportedAt.Before(report.RecordedAt) {
+			continue
+		}
+
+		recordedAt := report.RecordedAt.UTC()
+
+		v.Position = report.Position
+		v.BatteryLevel = report.BatteryLevel
+		v.LastReportedAt = &recordedAt
+		v.UpdatedAt = s.timestamp()
+		s.setVehicle(v)
+	}
+
+	return missing, nil
+}
+
+func (s *MemoryStore) Transition(ctx context.Context, id int64, to Status) (Vehicle, error) {
+	return s.transition(id, to.sources(), to)
+}
+
+func (s *MemoryStore) TransitionFrom(ctx context.Context, id int64, from, to Status) (Vehicle, error) {
+	return s.transition(id, to.sourcesAmong(from), to)
+}
+
+// transition changes the status of a vehicle to the to one, if its current status is one of from.
+func (s *MemoryStore) transition(id int64, from []Status, to Status) (Vehicle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.data[id]
+	if !ok {
+		return Vehicle{}, ErrNotFound
+	}
+
+	if !slices.Contains(from, v.Status) {
+		return Vehicle{}, &TransitionError{From: v.Status, To: to}
+	}
+
+	v.Status = to
+	v.UpdatedAt = s.timestamp()
+	s.setVehicle(v)
+
+	return v, nil
+}
+
+func (s *MemoryStore) FindClosestFrom(ctx context.Context, location Point, opts ListOptions) ([]Vehicle, *Cursor, error) {
+	vehicles, next, err := s.find(opts, func(v Vehicle) (float64, bool) {
+		return distance(location, v.Position), true
+	})
+
+	return withDirections(vehicles, location), next, err
+}
+
+func (s *MemoryStore) FindWithinRadius(ctx context.Context, location Point, radius float64, opts ListOptions) ([]Vehicle, *Cursor, error) {
+	vehicles, next, err := s.find(opts, func(v Vehicle) (float64, bool) {
+		d := distance(location, v.Position)
+		return d, d <= radius
+	})
+
+	return withDirections(vehicles, location), next, err
+}
+
+// withDirections sets the distance and bearing of the vehicles from location.
+func withDirections(vehicles []Vehicle, location Point) []Vehicle {
+	for i, v := range vehicles {
+		d := distance(location, v.Position)
+		vehicles[i].Distance = &d
+
+		if b, ok := bearing(location, v.Position); ok {
+			vehicles[i].Bearing = &b
+		}
+	}
+
+	return vehicles
+}
+
+func (s *MemoryStore) FindInBoundingBox(ctx context.Context, box BoundingBox, opts ListOptions) ([]Vehicle, *Cursor, error) {
+	return s.find(opts, func(v Vehicle) (float64, bool) {
+		return 0, box.contains(v.Position)
+	})
+}
+
+func (s *MemoryStore) FindInPolygon(ctx context.Context, polygon Polygon, opts ListOptions) ([]Vehicle, *Cursor, error) {
+	return s.find(opts, func(v Vehicle) (float64, bool) {
+		return 0, polygon.contains(v.Position)
+	})
+}
+
+// find returns the vehicles matched by match and the options, ordered by the distance
+// returned by match then by ID. See Store for the pagination.
+func (s *MemoryStore) find(opts ListOptions, match func(Vehicle) (float64, bool)) ([]Vehicle, *Cursor, error) {
+	if opts.Limit < 0 {
+		return nil, nil, errNegativeLimit
+	}
+
+	type candidate struct {
+		vehicle Vehicle
+		cursor  Cursor
+	}
+
+	s.mu.RLock()
+	candidates := make([]candidate, 0, len(s.data))
+	for _, v := range s.data {
+		d, ok := match(v)
+		if !ok || !opts.Filter.matches(v) {
+			continue
+		}
+
+		c := Cursor{Distance: d, ID: v.ID}
+		if opts.After != nil && compareCursors(c, *opts.After) <= 0 {
+			continue
+		}
+
+		candidates = append(candidates, candidate{vehicle: v, cursor: c})
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return compareCursors(a.cursor, b.cursor)
+	})
+
+	var (
+		vehicles []Vehicle
+		next     *Cursor
+	)
+
+	for i, c := range candidates {
+		if opts.Limit > 0 && int64(i) == opts.Limit {
+			next = &candidates[i-1].cursor
+			break
+		}
+
+		vehicles = append(vehicles, c.vehicle)
+	}
+
+	return vehicles, next, nil
+}
+
+// compareCursors orders cursors by distance, and break ties on the ID.
+func compareCursors(a, b Cursor) int {
+	return cmp.Or(
+		cmp.Compare(a.Distance, b.Distance),
+		cmp.Compare(a.ID, b.ID),
+	)
 }
 
 func (s *MemoryStore) Delete(ctx context.Context, id int64) (bool, error) {
-	return false, errors.New("not implemented")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.data[id]
+	if !ok {
+		return false, nil
+	}
+
+	switch v.Status {
+	case StatusReserved:
+		return false, ErrReserved
+	case StatusInUse:
+		return false, ErrInUse
+	}
+
+	s.dataUndo.Save(s.data, id)
+	delete(s.data, id)
+	s.trashUndo.Save(s.trash, id)
+	s.trash[id] = deletedVehicle{Vehicle: v, deletedAt: s.timestamp()}
+
+	return true, nil
+}
+
+func (s *MemoryStore) Restore(ctx context.Context, id int64) (Vehicle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.trash[id]
+	if !ok {
+		return Vehicle{}, ErrNotFound
+	}
+
+	s.trashUndo.Save(s.trash, id)
+	delete(s.trash, id)
+	v.UpdatedAt = s.timestamp()
+	s.setVehicle(v.Vehicle)
+
+	return v.Vehicle, nil
+}
+
+func (s *MemoryStore) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	purged, err := s.PurgeIDs(ctx, deletedBefore)
+
+	return int64(len(purged)), err
+}
+
+// PurgeIDs is Purge, returning the IDs of the purged vehicles so that their records
+// can be removed from the other stores.
+func (s *MemoryStore) PurgeIDs(ctx context.Context, deletedBefore time.Time) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged []int64
+
+	for id, v := range s.trash {
+		if v.deletedAt.Before(deletedBefore) {
+			s.trashUndo.Save(s.trash, id)
+			delete(s.trash, id)
+			purged = append(purged, id)
+		}
+	}
+
+	return purged, nil
 }
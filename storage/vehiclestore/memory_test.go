@@ -0,0 +1,48 @@
+//go:build !integration
+
+package vehiclestore_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore/vehiclestoretest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	vehiclestoretest.Run(t, func(t *testing.T) vehiclestore.Store {
+		return vehiclestore.NewMemoryStore()
+	})
+}
+
+func TestMemoryStore_ConcurrentCreates(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = vehiclestore.NewMemoryStore()
+		wg    sync.WaitGroup
+	)
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := store.Create(ctx, vehiclestore.Vehicle{ShortCode: fmt.Sprintf("v%03d", i)})
+			assert.NoError(t, err)
+
+			_, _, err = store.FindClosestFrom(ctx, vehiclestore.Point{}, vehiclestore.ListOptions{Limit: 10})
+			assert.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+
+	vehicles, _, err := store.FindClosestFrom(ctx, vehiclestore.Point{}, vehiclestore.ListOptions{Limit: 100})
+	require.NoError(t, err)
+	assert.Len(t, vehicles, 50)
+}
@@ -1,10 +1,14 @@
 package vehiclestore
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"time"
 
 	pkgpgx "github.com/Cirederf1/vehicle-server/pkg/pgx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	geom "github.com/twpayne/go-geom"
 	"github.com/twpayne/go-geom/encoding/ewkbhex"
 )
@@ -17,22 +21,26 @@ func NewPGXStore(conn pkgpgx.DB) *PGXStore {
 	return &PGXStore{conn: conn}
 }
 
+// vehicleColumns are the columns read by scanVehicle.
+const vehicleColumns = `id, shortcode, battery, position, type, attributes, status, created_at, updated_at, last_reported_at`
+
 const createVehicleStatement = `
-INSERT INTO vehicle_server.vehicles (shortcode, battery, position) VALUES ($1, $2, $3) RETURNING id;
+INSERT INTO vehicle_server.vehicles (shortcode, battery, position, type, attributes)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at, updated_at;
 `
 
 func (p *PGXStore) Create(ctx context.Context, v Vehicle) (Vehicle, error) {
-	encodedPos, err := ewkbhex.Encode(
-		geom.NewPoint(geom.XY).
-			MustSetCoords([]float64{v.Position.Longitude, v.Position.Latitude}).
-			SetSRID(4326),
-		ewkbhex.NDR,
-	)
+	encodedPos, err := encodePoint(v.Position)
 	if err != nil {
 		return Vehicle{}, err
 	}
 
-	var id int64
+	var (
+		id                   int64
+		createdAt, updatedAt time.Time
+		vehicleType          = cmp.Or(v.Type, TypeScooter)
+	)
 
 	err = p.conn.QueryRow(
 		ctx,
@@ -40,7 +48,12 @@ func (p *PGXStore) Create(ctx context.Context, v Vehicle) (Vehicle, error) {
 		v.ShortCode,
 		v.BatteryLevel,
 		encodedPos,
-	).Scan(&id)
+		vehicleType,
+		v.Attributes,
+	).Scan(&id, &createdAt, &updatedAt)
+	if isShortCodeViolation(err) {
+		return Vehicle{}, ErrDuplicateShortCode
+	}
 	if err != nil {
 		return Vehicle{}, err
 	}
@@ -50,69 +63,553 @@ func (p *PGXStore) Create(ctx context.Context, v Vehicle) (Vehicle, error) {
 		ShortCode:    v.ShortCode,
 		BatteryLevel: v.BatteryLevel,
 		Position:     v.Position,
+		Type:         vehicleType,
+		Attributes:   v.Attributes,
+		Status:       StatusAvailable,
+		CreatedAt:    createdAt.UTC(),
+		UpdatedAt:    updatedAt.UTC(),
 	}, nil
 }
 
+const getByIDStatement = `
+SELECT ` + vehicleColumns + `
+FROM vehicle_server.vehicles
+WHERE id = $1 AND deleted_at IS NULL;
+`
+
+func (p *PGXStore) Get(ctx context.Context, id int64) (Vehicle, error) {
+	v, err := scanVehicle(p.conn.QueryRow(ctx, getByIDStatement, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Vehicle{}, ErrNotFound
+	}
+
+	return v, err
+}
+
+const getByShortCodeStatement = `
+SELECT ` + vehicleColumns + `
+FROM vehicle_server.vehicles
+WHERE shortcode = $1 AND deleted_at IS NULL;
+`
+
+func (p *PGXStore) GetByShortCode(ctx context.Context, shortCode string) (Vehicle, error) {
+	v, err := scanVehicle(p.conn.QueryRow(ctx, getByShortCodeStatement, shortCode))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Vehicle{}, ErrNotFound
+	}
+
+	return v, err
+}
+
+const updateByIDStatement = `
+UPDATE vehicle_server.vehicles
+SET shortcode = $2, battery = $3, position = $4, type = $5, attributes = $6, updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING status, created_at, updated_at, last_reported_at;
+`
+
+func (p *PGXStore) Update(ctx context.Context, v Vehicle) (Vehicle, error) {
+	encodedPos, err := encodePoint(v.Position)
+	if err != nil {
+		return Vehicle{}, err
+	}
+
+	v.Type = cmp.Or(v.Type, TypeScooter)
+
+	err = p.conn.QueryRow(
+		ctx,
+		updateByIDStatement,
+		v.ID,
+		v.ShortCode,
+		v.BatteryLevel,
+		encodedPos,
+		v.Type,
+		v.Attributes,
+	).Scan(&v.Status, &v.CreatedAt, &v.UpdatedAt, &v.LastReportedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Vehicle{}, ErrNotFound
+	}
+	if isShortCodeViolation(err) {
+		return Vehicle{}, ErrDuplicateShortCode
+	}
+	if err != nil {
+		return Vehicle{}, err
+	}
+
+	v.CreatedAt, v.UpdatedAt = v.CreatedAt.UTC(), v.UpdatedAt.UTC()
+	v.LastReportedAt = utc(v.LastReportedAt)
+	v.Distance, v.Bearing = nil, nil
+
+	return v, nil
+}
+
+// reportPositionStatement tells whether the vehicle exists,
+// and only updates it when the report is more recent than the last one applied.
+const reportPositionStatement = `
+WITH reported AS (
+	UPDATE vehicle_server.vehicles
+	SET battery = @battery, position = @position, last_reported_at = @recorded_at, updated_at = now()
+	WHERE id = @id AND deleted_at IS NULL
+	AND (last_reported_at IS NULL OR last_reported_at < @recorded_at)
+	RETURNING id
+)
+SELECT EXISTS (SELECT 1 FROM reported)
+OR EXISTS (SELECT 1 FROM vehicle_server.vehicles WHERE id = @id AND deleted_at IS NULL);
+`
+
+func (p *PGXStore) ReportPositions(ctx context.Context, reports []PositionReport) ([]int64, error) {
+	if len(reports) == 0 {
+		return nil, nil
+	}
+
+	var batch pgx.Batch
+
+	for _, report := range reports {
+		encodedPos, err := encodePoint(report.Position)
+		if err != nil {
+			return nil, err
+		}
+
+		batch.Queue(reportPositionStatement, pgx.NamedArgs{
+			"id":          report.ID,
+			"battery":     report.BatteryLevel,
+			"position":    encodedPos,
+			"recorded_at": report.RecordedAt,
+		})
+	}
+
+	results := p.conn.SendBatch(ctx, &batch)
+	defer results.Close()
+
+	var missing []int64
+
+	for _, report := range reports {
+		var exists bool
+
+		if err := results.QueryRow().Scan(&exists); err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			missing = append(missing, report.ID)
+		}
+	}
+
+	return missing, results.Close()
+}
+
+// transitionStatement only changes the status when the current one is one of from, NULL matches none.
+const transitionStatement = `
+UPDATE vehicle_server.vehicles
+SET status = @to, updated_at = now()
+WHERE id = @id AND status = ANY(@from) AND deleted_at IS NULL
+RETURNING ` + vehicleColumns + `;
+`
+
+func (p *PGXStore) Transition(ctx context.Context, id int64, to Status) (Vehicle, error) {
+	return p.transition(ctx, id, to.sources(), to)
+}
+
+func (p *PGXStore) TransitionFrom(ctx context.Context, id int64, from, to Status) (Vehicle, error) {
+	return p.transition(ctx, id, to.sourcesAmong(from), to)
+}
+
+// transition changes the status of a vehicle to the to one, if its current status is one of from.
+func (p *PGXStore) transition(ctx context.Context, id int64, from []Status, to Status) (Vehicle, error) {
+	v, err := scanVehicle(p.conn.QueryRow(
+		ctx,
+		transitionStatement,
+		pgx.NamedArgs{"id": id, "to": string(to), "from": statusNames(from)},
+	))
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return v, err
+	}
+
+	// Either the vehicle does not exist, or its status cannot change.
+	current, err := p.Get(ctx, id)
+	if err != nil {
+		return Vehicle{}, err
+	}
+
+	return Vehicle{}, &TransitionError{From: current.Status, To: to}
+}
+
+// distanceExpression is the distance in meters between the vehicle and the searched location.
+// It is the one of the geography KNN operator, used for both ordering and paginating.
+const distanceExpression = `position::geography <-> ST_MakePoint(@longitude, @latitude)::geography`
+
+// bearingExpression is the bearing in degrees from the searched location towards the vehicle,
+// NULL when they are at the same position.
+const bearingExpression = `degrees(ST_Azimuth(ST_MakePoint(@longitude, @latitude)::geography, position::geography))`
+
+// The conditions below match the arguments returned by listArgs.
+const (
+	afterDistanceCondition = `(@after_distance::float8 IS NULL OR (` + distanceExpression + `, id) > (@after_distance, @after_id))`
+	afterIDCondition       = `(@after_id::bigint IS NULL OR id > @after_id)`
+	filterCondition        = `deleted_at IS NULL
+AND (@min_battery::smallint IS NULL OR battery >= @min_battery)
+AND (@max_battery::smallint IS NULL OR battery <= @max_battery)
+AND (@statuses::text[] IS NULL OR status = ANY(@statuses))
+AND (@updated_since::timestamptz IS NULL OR updated_at >= @updated_since)
+AND (@types::text[] IS NULL OR type = ANY(@types))`
+)
+
 const findClosestFromStatement = `
-SELECT id, shortcode, battery, position
+SELECT ` + vehicleColumns + `, ` + distanceExpression + ` AS distance, ` + bearingExpression + ` AS bearing
 FROM vehicle_server.vehicles
-ORDER BY position <-> ST_MakePoint($1, $2)::geography ASC
-LIMIT $3;
+WHERE ` + afterDistanceCondition + `
+AND ` + filterCondition + `
+ORDER BY distance ASC, id ASC
+LIMIT @limit;
 `
 
 var errInvalidCoordinates = errors.New("invalid coordinates")
 
-func (p *PGXStore) FindClosestFrom(ctx context.Context, location Point, limit int64) ([]Vehicle, error) {
-	var vehicles []Vehicle
+func (p *PGXStore) FindClosestFrom(ctx context.Context, location Point, opts ListOptions) ([]Vehicle, *Cursor, error) {
+	if opts.Limit < 0 {
+		return nil, nil, errNegativeLimit
+	}
+
+	args := listArgs(opts)
+	args["longitude"] = location.Longitude
+	args["latitude"] = location.Latitude
+
+	return p.findPage(ctx, opts.Limit, findClosestFromStatement, args)
+}
+
+const findWithinRadiusStatement = `
+SELECT ` + vehicleColumns + `, ` + distanceExpression + ` AS distance, ` + bearingExpression + ` AS bearing
+FROM vehicle_server.vehicles
+WHERE ST_DWithin(position::geography, ST_MakePoint(@longitude, @latitude)::geography, @radius)
+AND ` + afterDistanceCondition + `
+AND ` + filterCondition + `
+ORDER BY distance ASC, id ASC
+LIMIT @limit;
+`
+
+func (p *PGXStore) FindWithinRadius(ctx context.Context, location Point, radius float64, opts ListOptions) ([]Vehicle, *Cursor, error) {
+	if opts.Limit < 0 {
+		return nil, nil, errNegativeLimit
+	}
+
+	args := listArgs(opts)
+	args["longitude"] = location.Longitude
+	args["latitude"] = location.Latitude
+	args["radius"] = radius
+
+	return p.findPage(ctx, opts.Limit, findWithinRadiusStatement, args)
+}
+
+const findInBoundingBoxStatement = `
+SELECT ` + vehicleColumns + `, NULL::float8 AS distance, NULL::float8 AS bearing
+FROM vehicle_server.vehicles
+WHERE ST_Intersects(position, ST_MakeEnvelope(@min_longitude, @min_latitude, @max_longitude, @max_latitude, 4326))
+AND ` + afterIDCondition + `
+AND ` + filterCondition + `
+ORDER BY id ASC
+LIMIT @limit;
+`
+
+func (p *PGXStore) FindInBoundingBox(ctx context.Context, box BoundingBox, opts ListOptions) ([]Vehicle, *Cursor, error) {
+	if opts.Limit < 0 {
+		return nil, nil, errNegativeLimit
+	}
+
+	args := listArgs(opts)
+	args["min_longitude"] = box.Min.Longitude
+	args["min_latitude"] = box.Min.Latitude
+	args["max_longitude"] = box.Max.Longitude
+	args["max_latitude"] = box.Max.Latitude
+
+	return p.findPage(ctx, opts.Limit, findInBoundingBoxStatement, args)
+}
+
+const findInPolygonStatement = `
+SELECT ` + vehicleColumns + `, NULL::float8 AS distance, NULL::float8 AS bearing
+FROM vehicle_server.vehicles
+WHERE ST_Intersects(position, @polygon::geometry)
+AND ` + afterIDCondition + `
+AND ` + filterCondition + `
+ORDER BY id ASC
+LIMIT @limit;
+`
+
+func (p *PGXStore) FindInPolygon(ctx context.Context, polygon Polygon, opts ListOptions) ([]Vehicle, *Cursor, error) {
+	if opts.Limit < 0 {
+		return nil, nil, errNegativeLimit
+	}
 
-	rows, err := p.conn.Query(ctx, findClosestFromStatement, location.Longitude, location.Latitude, limit)
+	encodedPolygon, err := encodePolygon(polygon)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	args := listArgs(opts)
+	args["polygon"] = encodedPolygon
+
+	return p.findPage(ctx, opts.Limit, findInPolygonStatement, args)
+}
+
+// listArgs returns the pagination and filter arguments shared by the searches.
+func listArgs(opts ListOptions) pgx.NamedArgs {
+	args := pgx.NamedArgs{
+		// LIMIT NULL does not limit the results.
+		"limit":          nil,
+		"after_distance": nil,
+		"after_id":       nil,
+		"min_battery":    opts.Filter.MinBattery,
+		"max_battery":    opts.Filter.MaxBattery,
+		"statuses":       statusNames(opts.Filter.Statuses),
+		"updated_since":  opts.Filter.UpdatedSince,
+		"types":          typeNames(opts.Filter.Types),
+	}
+
+	if opts.Limit > 0 {
+		// Fetch one more vehicle to know if there is a next page.
+		args["limit"] = opts.Limit + 1
+	}
+
+	if opts.After != nil {
+		args["after_distance"] = opts.After.Distance
+		args["after_id"] = opts.After.ID
+	}
+
+	return args
+}
+
+// findPage runs a query selecting vehicles followed by their distance and bearing,
+// both NULL for the listings ordered by ID, and keeps at most limit of them, unless limit is 0.
+// The query must select one more vehicle than the limit when there is a next page.
+func (p *PGXStore) findPage(ctx context.Context, limit int64, sql string, args pgx.NamedArgs) ([]Vehicle, *Cursor, error) {
+	rows, err := p.conn.Query(ctx, sql, args)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer rows.Close()
 
+	var (
+		vehicles []Vehicle
+		cursors  []Cursor
+	)
+
 	for rows.Next() {
-		var (
-			v          Vehicle
-			encodedPos string
-		)
+		var distance, bearing *float64
 
-		if err := rows.Scan(
+		v, err := scanVehicle(rows, &distance, &bearing)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		v.Distance, v.Bearing = distance, bearing
+
+		c := Cursor{ID: v.ID}
+		if distance != nil {
+			c.Distance = *distance
+		}
+
+		vehicles = append(vehicles, v)
+		cursors = append(cursors, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if limit == 0 || int64(len(vehicles)) <= limit {
+		return vehicles, nil, nil
+	}
+
+	return vehicles[:limit], &cursors[limit-1], nil
+}
+
+// shortCodeConstraint is the unique constraint on the shortcodes, see the 0003 migration.
+const shortCodeConstraint = "vehicles_shortcode_key"
+
+// uniqueViolation is the SQLSTATE of the unique constraint violations.
+const uniqueViolation = "23505"
+
+func isShortCodeViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) &&
+		pgErr.Code == uniqueViolation &&
+		pgErr.ConstraintName == shortCodeConstraint
+}
+
+// statusNames converts the statuses to a text array, nil stays NULL.
+func statusNames(statuses []Status) []string {
+	if statuses == nil {
+		return nil
+	}
+
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+
+	return names
+}
+
+// typeNames converts the types to a text array, nil stays NULL.
+func typeNames(types []Type) []string {
+	if types == nil {
+		return nil
+	}
+
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+
+	return names
+}
+
+// encodePolygon encodes a polygon as hex EWKB with the WGS 84 SRID.
+func encodePolygon(polygon Polygon) (string, error) {
+	rings := make([][]geom.Coord, len(polygon))
+	for i, ring := range polygon {
+		rings[i] = make([]geom.Coord, len(ring))
+		for j, p := range ring {
+			rings[i][j] = geom.Coord{p.Longitude, p.Latitude}
+		}
+	}
+
+	g, err := geom.NewPolygon(geom.XY).SetCoords(rings)
+	if err != nil {
+		return "", err
+	}
+
+	return ewkbhex.Encode(g.SetSRID(4326), ewkbhex.NDR)
+}
+
+// encodePoint encodes a position as hex EWKB with the WGS 84 SRID.
+func encodePoint(p Point) (string, error) {
+	return ewkbhex.Encode(
+		geom.NewPoint(geom.XY).
+			MustSetCoords([]float64{p.Longitude, p.Latitude}).
+			SetSRID(4326),
+		ewkbhex.NDR,
+	)
+}
+
+// scanVehicle reads a vehicle from a row selecting the vehicleColumns first,
+// the columns selected after them are scanned into extra.
+func scanVehicle(row pgx.Row, extra ...any) (Vehicle, error) {
+	var (
+		v          Vehicle
+		encodedPos string
+	)
+
+	if err := row.Scan(append(
+		[]any{
 			&v.ID,
 			&v.ShortCode,
 			&v.BatteryLevel,
 			&encodedPos,
-		); err != nil {
-			return nil, err
-		}
+			&v.Type,
+			&v.Attributes,
+			&v.Status,
+			&v.CreatedAt,
+			&v.UpdatedAt,
+			&v.LastReportedAt,
+		},
+		extra...,
+	)...); err != nil {
+		return Vehicle{}, err
+	}
 
-		point, err := ewkbhex.Decode(encodedPos)
-		if err != nil {
-			return nil, err
-		}
-		coords := point.FlatCoords()
-		if len(coords) != 2 {
-			return nil, errInvalidCoordinates
-		}
+	point, err := ewkbhex.Decode(encodedPos)
+	if err != nil {
+		return Vehicle{}, err
+	}
+	coords := point.FlatCoords()
+	if len(coords) != 2 {
+		return Vehicle{}, errInvalidCoordinates
+	}
 
-		v.Position.Longitude = coords[0]
-		v.Position.Latitude = coords[1]
+	v.Position.Longitude = coords[0]
+	v.Position.Latitude = coords[1]
+	// pgx reads the timestamps in the local time zone.
+	v.CreatedAt, v.UpdatedAt = v.CreatedAt.UTC(), v.UpdatedAt.UTC()
+	v.LastReportedAt = utc(v.LastReportedAt)
 
-		vehicles = append(vehicles, v)
+	return v, nil
+}
+
+// utc converts an optional timestamp to UTC.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
 	}
 
-	return vehicles, rows.Err()
+	u := t.UTC()
+
+	return &u
 }
 
 const deleteByIDStatement = `
-DELETE FROM vehicle_server.vehicles WHERE id = $1
+UPDATE vehicle_server.vehicles SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL AND status NOT IN ($2, $3)
+`
+
+// statusByIDStatement tells why a vehicle was not deleted, it runs after the deletion
+// to see the status committed by a concurrent transition.
+const statusByIDStatement = `
+SELECT status FROM vehicle_server.vehicles WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (p *PGXStore) Delete(ctx context.Context, id int64) (bool, error) {
-	tag, err := p.conn.Exec(ctx, deleteByIDStatement, id)
+	tag, err := p.conn.Exec(ctx, deleteByIDStatement, id, string(StatusReserved), string(StatusInUse))
 	if err != nil {
 		return false, err
 	}
 
-	return tag.RowsAffected() == 1, nil
+	if tag.RowsAffected() == 1 {
+		return true, nil
+	}
+
+	var status Status
+
+	err = p.conn.QueryRow(ctx, statusByIDStatement, id).Scan(&status)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return false, nil
+	case err != nil:
+		return false, err
+	case status == StatusReserved:
+		return false, ErrReserved
+	case status == StatusInUse:
+		return false, ErrInUse
+	}
+
+	// The vehicle was released since the deletion, it is deleted now.
+	return p.Delete(ctx, id)
+}
+
+const restoreByIDStatement = `
+UPDATE vehicle_server.vehicles
+SET deleted_at = NULL, updated_at = now()
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING ` + vehicleColumns + `;
+`
+
+func (p *PGXStore) Restore(ctx context.Context, id int64) (Vehicle, error) {
+	v, err := scanVehicle(p.conn.QueryRow(ctx, restoreByIDStatement, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Vehicle{}, ErrNotFound
+	}
+
+	return v, err
+}
+
+const purgeStatement = `
+DELETE FROM vehicle_server.vehicles WHERE deleted_at < $1
+`
+
+func (p *PGXStore) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	tag, err := p.conn.Exec(ctx, purgeStatement, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
 }
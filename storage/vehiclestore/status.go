@@ -0,0 +1,79 @@
+package vehiclestore
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Status is the lifecycle state of a vehicle.
+type Status string
+
+const (
+	// StatusAvailable vehicles can be rented, it is the status of the new vehicles.
+	StatusAvailable Status = "available"
+	StatusReserved  Status = "reserved"
+	StatusInUse     Status = "in_use"
+	// StatusMaintenance vehicles are in the workshop.
+	StatusMaintenance Status = "maintenance"
+	// StatusRetired vehicles left the fleet for good.
+	StatusRetired Status = "retired"
+)
+
+// Statuses lists every status.
+var Statuses = []Status{StatusAvailable, StatusReserved, StatusInUse, StatusMaintenance, StatusRetired}
+
+// transitions is the graph of the allowed status changes.
+var transitions = map[Status][]Status{
+	StatusAvailable:   {StatusReserved, StatusInUse, StatusMaintenance, StatusRetired},
+	StatusReserved:    {StatusAvailable, StatusInUse, StatusMaintenance},
+	StatusInUse:       {StatusAvailable, StatusMaintenance},
+	StatusMaintenance: {StatusAvailable, StatusRetired},
+	StatusRetired:     nil,
+}
+
+// Valid tells whether s is one of the Statuses.
+func (s Status) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// CanTransitionTo tells whether the transition graph allows changing from s to the to status.
+func (s Status) CanTransitionTo(to Status) bool {
+	return slices.Contains(transitions[s], to)
+}
+
+// sources returns the statuses allowed to change to s.
+func (s Status) sources() []Status {
+	return s.sourcesAmong(Statuses...)
+}
+
+// sourcesAmong returns the statuses of from allowed to change to s.
+func (s Status) sourcesAmong(from ...Status) []Status {
+	var allowed []Status
+
+	for _, status := range from {
+		if status.CanTransitionTo(s) {
+			allowed = append(allowed, status)
+		}
+	}
+
+	return allowed
+}
+
+// ErrInvalidTransition is matched by the TransitionError.
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// TransitionError is returned when the transition graph does not allow a status change.
+type TransitionError struct {
+	From Status
+	To   Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s: from %s to %s", ErrInvalidTransition, e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
@@ -1,27 +1,185 @@
 package vehiclestore
 
-import "context"
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// ErrNotFound is returned when the requested vehicle does not exist.
+var ErrNotFound = errors.New("vehicle not found")
+
+// ErrDuplicateShortCode is returned when the shortcode is already used by another vehicle.
+var ErrDuplicateShortCode = errors.New("shortcode already used")
+
+// ErrInUse is returned when deleting a vehicle in an ongoing trip.
+var ErrInUse = errors.New("vehicle in use")
+
+// ErrReserved is returned when deleting a vehicle held by an active reservation.
+var ErrReserved = errors.New("vehicle reserved")
+
+var errNegativeLimit = errors.New("limit must not be negative")
 
 type Point struct {
 	Latitude  float64
 	Longitude float64
 }
 
+// Cursor is the position of a vehicle in a listing, the next page starts right after it.
+type Cursor struct {
+	// Distance in meters from the searched location,
+	// always 0 for the listings ordered by ID.
+	Distance float64
+	ID       int64
+}
+
+// ListOptions narrows down and paginates the vehicle searches.
+type ListOptions struct {
+	// Limit is the maximum number of vehicles returned, 0 means no limit.
+	Limit int64
+	// After is the cursor returned along the previous page, nil for the first page.
+	After  *Cursor
+	Filter Filter
+}
+
+// Filter holds the predicates the vehicles must match, nil fields match every vehicle.
+type Filter struct {
+	MinBattery *int64
+	MaxBattery *int64
+	// Statuses matches the vehicles having one of them.
+	Statuses []Status
+	// UpdatedSince matches the vehicles updated at or after it.
+	UpdatedSince *time.Time
+	// Types matches the vehicles having one of them.
+	Types []Type
+}
+
+func (f Filter) matches(v Vehicle) bool {
+	if f.Statuses != nil && !slices.Contains(f.Statuses, v.Status) {
+		return false
+	}
+
+	if f.Types != nil && !slices.Contains(f.Types, v.Type) {
+		return false
+	}
+
+	if f.MinBattery != nil && v.BatteryLevel < *f.MinBattery {
+		return false
+	}
+
+	if f.MaxBattery != nil && v.BatteryLevel > *f.MaxBattery {
+		return false
+	}
+
+	if f.UpdatedSince != nil && v.UpdatedAt.Before(*f.UpdatedSince) {
+		return false
+	}
+
+	return true
+}
+
+// BoundingBox is the area between two corners, it does not cross the antimeridian.
+type BoundingBox struct {
+	Min Point
+	Max Point
+}
+
+// Polygon is made of a closed exterior ring, optionally followed by closed rings of holes.
+type Polygon [][]Point
+
 type Vehicle struct {
 	ID           int64
 	ShortCode    string
 	Position     Point
 	BatteryLevel int64
+	// Type is TypeScooter when creating or updating a vehicle without one.
+	Type       Type
+	Attributes Attributes
+	// Status is StatusAvailable when creating a vehicle, and only changed by the transitions.
+	Status Status
+	// CreatedAt and UpdatedAt are set by the stores, UpdatedAt changes with every
+	// update, transition and restore of the vehicle.
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// LastReportedAt is the time the latest applied position report was recorded at,
+	// nil until the vehicle reports its position.
+	LastReportedAt *time.Time
+
+	// Distance is the great-circle distance in meters from the searched location,
+	// and Bearing the initial bearing in degrees clockwise from the north towards the vehicle.
+	// They are only set by the nearest-first searches, and Bearing is nil at the searched location.
+	Distance *float64
+	Bearing  *float64
+}
+
+// PositionReport is the position and battery level reported by a vehicle.
+type PositionReport struct {
+	ID           int64
+	Position     Point
+	BatteryLevel int64
+	RecordedAt   time.Time
 }
 
 type Store interface {
 	// Creates a new vehicle.
+	// It returns ErrDuplicateShortCode if the shortcode is already used.
 	Create(context.Context, Vehicle) (Vehicle, error)
 
-	// Finds the N closests vehicles from the current position.
-	FindClosestFrom(context.Context, Point, int64) ([]Vehicle, error)
+	// Get a vehicle by its ID.
+	// It returns ErrNotFound if the id does not exist.
+	Get(context.Context, int64) (Vehicle, error)
+
+	// GetByShortCode gets a vehicle by its shortcode.
+	// It returns ErrNotFound if no vehicle uses the shortcode.
+	GetByShortCode(context.Context, string) (Vehicle, error)
+
+	// Update replaces the shortcode, position, battery level, type and attributes of an existing vehicle.
+	// It returns ErrNotFound if the id does not exist,
+	// and ErrDuplicateShortCode if the shortcode is used by another vehicle.
+	Update(context.Context, Vehicle) (Vehicle, error)
+
+	// ReportPositions replaces the position and battery level of many vehicles at once.
+	// The reports recorded at or before the last one applied to their vehicle are ignored,
+	// so that the delayed ones do not move the vehicles back.
+	// It returns the IDs of the reports whose vehicle does not exist, the others are applied or ignored.
+	ReportPositions(context.Context, []PositionReport) (missing []int64, err error)
 
-	// Delete a vehicle by its ID.
-	// It returns true if the vehicle was deleted, false if the id did not exist.
+	// Transition changes the status of a vehicle, as allowed by the transition graph.
+	// It returns ErrNotFound if the id does not exist, and a *TransitionError
+	// matching ErrInvalidTransition if the current status cannot change to the new one.
+	Transition(ctx context.Context, id int64, to Status) (Vehicle, error)
+
+	// TransitionFrom is Transition, only when the current status is the from one.
+	// It returns a *TransitionError from the current status otherwise.
+	TransitionFrom(ctx context.Context, id int64, from, to Status) (Vehicle, error)
+
+	// The searches below return the vehicles matching the options, along with
+	// the cursor of the next page, nil when there are no more vehicles.
+
+	// Finds the closests vehicles from the current position.
+	FindClosestFrom(ctx context.Context, location Point, opts ListOptions) ([]Vehicle, *Cursor, error)
+
+	// Finds the vehicles at most radius meters away from the current position, closest first.
+	FindWithinRadius(ctx context.Context, location Point, radius float64, opts ListOptions) ([]Vehicle, *Cursor, error)
+
+	// Finds the vehicles inside a bounding box, ordered by ID.
+	FindInBoundingBox(ctx context.Context, box BoundingBox, opts ListOptions) ([]Vehicle, *Cursor, error)
+
+	// Finds the vehicles inside a polygon, ordered by ID.
+	FindInPolygon(ctx context.Context, polygon Polygon, opts ListOptions) ([]Vehicle, *Cursor, error)
+
+	// Delete soft deletes a vehicle by its ID: it is hidden from every other method,
+	// but keeps its shortcode until it is purged.
+	// It returns true if the vehicle was deleted, false if the id did not exist,
+	// and ErrReserved or ErrInUse if the vehicle is reserved or in use, so that its
+	// reservation or trip can still end and release it.
 	Delete(context.Context, int64) (bool, error)
+
+	// Restore undoes the deletion of a vehicle.
+	// It returns ErrNotFound if the id is not the one of a deleted vehicle.
+	Restore(context.Context, int64) (Vehicle, error)
+
+	// Purge permanently removes the vehicles deleted before a time, and returns their count.
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
 }
@@ -0,0 +1,30 @@
+package vehiclestore
+
+import "slices"
+
+// Type is the kind of a vehicle.
+type Type string
+
+const (
+	// TypeScooter is the type of the vehicles created without one.
+	TypeScooter Type = "scooter"
+	TypeBike    Type = "bike"
+	TypeMoped   Type = "moped"
+)
+
+// Types lists every type.
+var Types = []Type{TypeScooter, TypeBike, TypeMoped}
+
+// Valid tells whether t is one of the Types.
+func (t Type) Valid() bool {
+	return slices.Contains(Types, t)
+}
+
+// Attributes are the characteristics of a vehicle depending on its type,
+// nil fields are not relevant to the vehicle.
+type Attributes struct {
+	// HelmetBox tells whether a moped carries a box with helmets.
+	HelmetBox *bool `json:"helmet_box,omitempty"`
+	// Seats is the number of people the vehicle can carry.
+	Seats *int64 `json:"seats,omitempty"`
+}
@@ -0,0 +1,693 @@
+// Package vehiclestoretest holds the behavioural tests every
+// vehiclestore.Store implementation must pass.
+package vehiclestoretest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the behavioural tests, newStore must return an empty store.
+func Run(t *testing.T, newStore func(t *testing.T) vehiclestore.Store) {
+	t.Helper()
+
+	t.Run("creates and gets vehicles", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		first := create(t, store, "aaa", 50, 50, 40)
+		second := create(t, store, "bbb", 51, 51, 50)
+		assert.NotEqual(t, first.ID, second.ID)
+		assert.False(t, second.CreatedAt.IsZero())
+		assert.Equal(t, second.CreatedAt, second.UpdatedAt)
+		assert.Equal(t, vehiclestore.TypeScooter, second.Type)
+
+		got, err := store.Get(ctx, second.ID)
+		require.NoError(t, err)
+		assert.Equal(t, second, got)
+
+		_, err = store.Get(ctx, second.ID+100)
+		assert.ErrorIs(t, err, vehiclestore.ErrNotFound)
+	})
+
+	t.Run("gets vehicles by shortcode", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		create(t, store, "aaa", 50, 50, 40)
+		second := create(t, store, "bbb", 51, 51, 50)
+
+		got, err := store.GetByShortCode(ctx, "bbb")
+		require.NoError(t, err)
+		assert.Equal(t, second, got)
+
+		_, err = store.GetByShortCode(ctx, "ccc")
+		assert.ErrorIs(t, err, vehiclestore.ErrNotFound)
+	})
+
+	t.Run("keeps the shortcodes unique", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		first := create(t, store, "aaa", 50, 50, 40)
+		second := create(t, store, "bbb", 51, 51, 50)
+
+		_, err := store.Create(ctx, vehiclestore.Vehicle{ShortCode: "aaa", BatteryLevel: 10})
+		assert.ErrorIs(t, err, vehiclestore.ErrDuplicateShortCode)
+
+		second.ShortCode = "aaa"
+		_, err = store.Update(ctx, second)
+		assert.ErrorIs(t, err, vehiclestore.ErrDuplicateShortCode)
+
+		// A vehicle keeps its own shortcode.
+		first.BatteryLevel = 12
+		_, err = store.Update(ctx, first)
+		assert.NoError(t, err)
+	})
+
+	t.Run("updates vehicles", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		v := create(t, store, "aaa", 50, 50, 40)
+
+		v.ShortCode = "zzz"
+		v.BatteryLevel = 12
+		v.Position = vehiclestore.Point{Latitude: 10, Longitude: 11}
+
+		updated, err := store.Update(ctx, v)
+		require.NoError(t, err)
+		assert.Equal(t, v.CreatedAt, updated.CreatedAt)
+		assert.True(t, updated.UpdatedAt.After(v.UpdatedAt))
+
+		v.UpdatedAt = updated.UpdatedAt
+		assert.Equal(t, v, updated)
+
+		got, err := store.Get(ctx, v.ID)
+		require.NoError(t, err)
+		assert.Equal(t, v, got)
+
+		v.ID += 100
+		_, err = store.Update(ctx, v)
+		assert.ErrorIs(t, err, vehiclestore.ErrNotFound)
+	})
+
+	t.Run("keeps the vehicles type and attributes", func(t *testing.T) {
+		var (
+			ctx       = context.Background()
+			helmetBox = true
+			seats     = int64(2)
+		)
+
+		store := newStore(t)
+
+		v, err := store.Create(ctx, vehiclestore.Vehicle{
+			ShortCode:    "aaa",
+			BatteryLevel: 40,
+			Type:         vehiclestore.TypeMoped,
+			Attributes:   vehiclestore.Attributes{HelmetBox: &helmetBox, Seats: &seats},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, vehiclestore.TypeMoped, v.Type)
+
+		got, err := store.Get(ctx, v.ID)
+		require.NoError(t, err)
+		assert.Equal(t, v, got)
+
+		v.Type = vehiclestore.TypeBike
+		v.Attributes = vehiclestore.Attributes{Seats: &seats}
+
+		v, err = store.Update(ctx, v)
+		require.NoError(t, err)
+		assert.Equal(t, vehiclestore.Attributes{Seats: &seats}, v.Attributes)
+
+		got, err = store.Get(ctx, v.ID)
+		require.NoError(t, err)
+		assert.Equal(t, v, got)
+	})
+
+	t.Run("reports the vehicles positions", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		first := create(t, store, "aaa", 50, 50, 40)
+		second := create(t, store, "bbb", 51, 51, 50)
+		deleted := create(t, store, "ccc", 52, 52, 60)
+
+		_, err := store.Delete(ctx, deleted.ID)
+		require.NoError(t, err)
+
+		recordedAt := time.Date(2024, time.March, 1, 14, 0, 0, 0, time.UTC)
+
+		missing, err := store.ReportPositions(ctx, []vehiclestore.PositionReport{
+			{ID: first.ID, Position: vehiclestore.Point{Latitude: 10, Longitude: 11}, BatteryLevel: 30, RecordedAt: recordedAt},
+			{ID: deleted.ID, Position: vehiclestore.Point{Latitude: 10, Longitude: 11}, BatteryLevel: 30, RecordedAt: recordedAt},
+			{ID: second.ID + 100, Position: vehiclestore.Point{Latitude: 10, Longitude: 11}, BatteryLevel: 30, RecordedAt: recordedAt},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{deleted.ID, second.ID + 100}, missing)
+
+		got, err := store.Get(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, vehiclestore.Point{Latitude: 10, Longitude: 11}, got.Position)
+		assert.Equal(t, int64(30), got.BatteryLevel)
+		assert.Equal(t, &recordedAt, got.LastReportedAt)
+		assert.True(t, got.UpdatedAt.After(first.UpdatedAt))
+
+		// The reports recorded at or before the last one are ignored, the vehicle still exists.
+		reported := got
+
+		for _, at := range []time.Time{recordedAt, recordedAt.Add(-time.Minute)} {
+			missing, err = store.ReportPositions(ctx, []vehiclestore.PositionReport{
+				{ID: first.ID, Position: vehiclestore.Point{Latitude: 20, Longitude: 21}, BatteryLevel: 90, RecordedAt: at},
+			})
+			require.NoError(t, err)
+			assert.Empty(t, missing)
+
+			got, err = store.Get(ctx, first.ID)
+			require.NoError(t, err)
+			assert.Equal(t, reported, got)
+		}
+
+		got, err = store.Get(ctx, second.ID)
+		require.NoError(t, err)
+		assert.Equal(t, second, got)
+	})
+
+	t.Run("transitions the vehicles status", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		v := create(t, store, "aaa", 50, 50, 40)
+		assert.Equal(t, vehiclestore.StatusAvailable, v.Status)
+
+		created := v
+
+		v, err := store.Transition(ctx, v.ID, vehiclestore.StatusMaintenance)
+		require.NoError(t, err)
+		assert.Equal(t, vehiclestore.StatusMaintenance, v.Status)
+		assert.Equal(t, created.CreatedAt, v.CreatedAt)
+		assert.True(t, v.UpdatedAt.After(created.UpdatedAt))
+
+		// The status survives the updates.
+		v.BatteryLevel = 90
+		v, err = store.Update(ctx, v)
+		require.NoError(t, err)
+		assert.Equal(t, vehiclestore.StatusMaintenance, v.Status)
+
+		_, err = store.Transition(ctx, v.ID, vehiclestore.StatusInUse)
+		assert.ErrorIs(t, err, vehiclestore.ErrInvalidTransition)
+
+		var transitionErr *vehiclestore.TransitionError
+		require.ErrorAs(t, err, &transitionErr)
+		assert.Equal(t, vehiclestore.TransitionError{From: vehiclestore.StatusMaintenance, To: vehiclestore.StatusInUse}, *transitionErr)
+
+		got, err := store.Get(ctx, v.ID)
+		require.NoError(t, err)
+		assert.Equal(t, v, got)
+
+		_, err = store.Transition(ctx, v.ID+100, vehiclestore.StatusAvailable)
+		assert.ErrorIs(t, err, vehiclestore.ErrNotFound)
+	})
+
+	t.Run("changes the status from an expected one", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		v, err := store.Create(ctx, vehiclestore.Vehicle{ShortCode: "aaa", BatteryLevel: 50})
+		require.NoError(t, err)
+
+		v, err = store.TransitionFrom(ctx, v.ID, vehiclestore.StatusAvailable, vehiclestore.StatusReserved)
+		require.NoError(t, err)
+		assert.Equal(t, vehiclestore.StatusReserved, v.Status)
+
+		// The graph allows the transition, but not from the current status.
+		_, err = store.TransitionFrom(ctx, v.ID, vehiclestore.StatusAvailable, vehiclestore.StatusInUse)
+
+		var transitionErr *vehiclestore.TransitionError
+		require.ErrorAs(t, err, &transitionErr)
+		assert.Equal(t, vehiclestore.TransitionError{From: vehiclestore.StatusReserved, To: vehiclestore.StatusInUse}, *transitionErr)
+
+		// Nor from a status which cannot change to the new one.
+		_, err = store.TransitionFrom(ctx, v.ID, vehiclestore.StatusReserved, vehiclestore.StatusRetired)
+		assert.ErrorIs(t, err, vehiclestore.ErrInvalidTransition)
+
+		v, err = store.TransitionFrom(ctx, v.ID, vehiclestore.StatusReserved, vehiclestore.StatusInUse)
+		require.NoError(t, err)
+		assert.Equal(t, vehiclestore.StatusInUse, v.Status)
+
+		_, err = store.TransitionFrom(ctx, v.ID+100, vehiclestore.StatusInUse, vehiclestore.StatusAvailable)
+		assert.ErrorIs(t, err, vehiclestore.ErrNotFound)
+	})
+
+	t.Run("finds the closest vehicles", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		far := create(t, store, "ccc", 52, 52, 60)
+		closest := create(t, store, "aaa", 50, 50, 40)
+		middle := create(t, store, "bbb", 51, 51, 50)
+
+		vehicles, _, err := store.FindClosestFrom(ctx, vehiclestore.Point{Latitude: 49, Longitude: 49}, vehiclestore.ListOptions{Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{closest, middle, far}, withoutDirections(vehicles))
+
+		vehicles, _, err = store.FindClosestFrom(ctx, vehiclestore.Point{Latitude: 53, Longitude: 53}, vehiclestore.ListOptions{Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{far, middle}, withoutDirections(vehicles))
+	})
+
+	t.Run("returns the distance and bearing of the closest vehicles", func(t *testing.T) {
+		var (
+			ctx      = context.Background()
+			store    = newStore(t)
+			location = vehiclestore.Point{Latitude: 50, Longitude: 50}
+			box      = vehiclestore.BoundingBox{
+				Min: vehiclestore.Point{Latitude: 40, Longitude: 40},
+				Max: vehiclestore.Point{Latitude: 60, Longitude: 60},
+			}
+		)
+
+		create(t, store, "aaa", 50, 50, 40)
+		create(t, store, "bbb", 51, 50, 50)
+		create(t, store, "ccc", 50, 51, 60)
+
+		for _, find := range []func() ([]vehiclestore.Vehicle, *vehiclestore.Cursor, error){
+			func() ([]vehiclestore.Vehicle, *vehiclestore.Cursor, error) {
+				return store.FindClosestFrom(ctx, location, vehiclestore.ListOptions{})
+			},
+			func() ([]vehiclestore.Vehicle, *vehiclestore.Cursor, error) {
+				return store.FindWithinRadius(ctx, location, 1_000_000, vehiclestore.ListOptions{})
+			},
+		} {
+			vehicles, _, err := find()
+			require.NoError(t, err)
+			require.Len(t, vehicles, 3)
+
+			// At the searched location, there is no bearing.
+			require.NotNil(t, vehicles[0].Distance)
+			assert.InDelta(t, 0, *vehicles[0].Distance, 1)
+			assert.Nil(t, vehicles[0].Bearing)
+
+			// One degree of longitude east, about 71km at this latitude.
+			require.NotNil(t, vehicles[1].Distance)
+			require.NotNil(t, vehicles[1].Bearing)
+			assert.InDelta(t, 71_500, *vehicles[1].Distance, 500)
+			assert.InDelta(t, 90, *vehicles[1].Bearing, 1)
+
+			// One degree of latitude north, about 111km.
+			require.NotNil(t, vehicles[2].Distance)
+			require.NotNil(t, vehicles[2].Bearing)
+			assert.InDelta(t, 111_200, *vehicles[2].Distance, 500)
+			assert.InDelta(t, 0, *vehicles[2].Bearing, 1)
+		}
+
+		// The areas are not searched from a location.
+		vehicles, _, err := store.FindInBoundingBox(ctx, box, vehiclestore.ListOptions{})
+		require.NoError(t, err)
+		require.Len(t, vehicles, 3)
+		assert.Nil(t, vehicles[0].Distance)
+		assert.Nil(t, vehicles[0].Bearing)
+	})
+
+	t.Run("paginates the closest vehicles", func(t *testing.T) {
+		var (
+			ctx      = context.Background()
+			store    = newStore(t)
+			location = vehiclestore.Point{Latitude: 49, Longitude: 49}
+		)
+
+		// Vehicles at the same position are ordered by ID.
+		want := []vehiclestore.Vehicle{
+			create(t, store, "aaa", 50, 50, 40),
+			create(t, store, "bbb", 50, 50, 50),
+			create(t, store, "ccc", 51, 51, 60),
+			create(t, store, "ddd", 52, 52, 70),
+			create(t, store, "eee", 53, 53, 80),
+		}
+
+		for _, find := range []struct {
+			desc string
+			page func(after *vehiclestore.Cursor) ([]vehiclestore.Vehicle, *vehiclestore.Cursor, error)
+		}{
+			{
+				desc: "closest",
+				page: func(after *vehiclestore.Cursor) ([]vehiclestore.Vehicle, *vehiclestore.Cursor, error) {
+					return store.FindClosestFrom(ctx, location, vehiclestore.ListOptions{Limit: 2, After: after})
+				},
+			},
+			{
+				desc: "within radius",
+				page: func(after *vehiclestore.Cursor) ([]vehiclestore.Vehicle, *vehiclestore.Cursor, error) {
+					return store.FindWithinRadius(ctx, location, 1_000_000, vehiclestore.ListOptions{Limit: 2, After: after})
+				},
+			},
+			{
+				desc: "in bounding box",
+				page: func(after *vehiclestore.Cursor) ([]vehiclestore.Vehicle, *vehiclestore.Cursor, error) {
+					box := vehiclestore.BoundingBox{
+						Min: vehiclestore.Point{Latitude: 40, Longitude: 40},
+						Max: vehiclestore.Point{Latitude: 60, Longitude: 60},
+					}
+
+					return store.FindInBoundingBox(ctx, box, vehiclestore.ListOptions{Limit: 2, After: after})
+				},
+			},
+		} {
+			var (
+				got   []vehiclestore.Vehicle
+				after *vehiclestore.Cursor
+				pages int
+			)
+
+			for {
+				vehicles, next, err := find.page(after)
+				require.NoError(t, err, find.desc)
+
+				got = append(got, withoutDirections(vehicles)...)
+				pages++
+
+				if next == nil {
+					break
+				}
+
+				require.Equal(t, vehicles[len(vehicles)-1].ID, next.ID, find.desc)
+				after = next
+			}
+
+			assert.Equal(t, want, got, find.desc)
+			assert.Equal(t, 3, pages, find.desc)
+		}
+	})
+
+	t.Run("filters the vehicles", func(t *testing.T) {
+		var (
+			ctx      = context.Background()
+			store    = newStore(t)
+			location = vehiclestore.Point{Latitude: 49, Longitude: 49}
+			box      = vehiclestore.BoundingBox{
+				Min: vehiclestore.Point{Latitude: 40, Longitude: 40},
+				Max: vehiclestore.Point{Latitude: 60, Longitude: 60},
+			}
+		)
+
+		create(t, store, "aaa", 50, 50, 10)
+		middle := create(t, store, "bbb", 51, 51, 50)
+		create(t, store, "ccc", 52, 52, 90)
+
+		var (
+			minBattery = int64(20)
+			maxBattery = int64(80)
+			opts       = vehiclestore.ListOptions{
+				Filter: vehiclestore.Filter{MinBattery: &minBattery, MaxBattery: &maxBattery},
+			}
+		)
+
+		vehicles, _, err := store.FindClosestFrom(ctx, location, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{middle}, withoutDirections(vehicles))
+
+		vehicles, _, err = store.FindWithinRadius(ctx, location, 1_000_000, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{middle}, withoutDirections(vehicles))
+
+		vehicles, _, err = store.FindInBoundingBox(ctx, box, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{middle}, vehicles)
+
+		middle, err = store.Transition(ctx, middle.ID, vehiclestore.StatusMaintenance)
+		require.NoError(t, err)
+
+		opts = vehiclestore.ListOptions{
+			Filter: vehiclestore.Filter{Statuses: []vehiclestore.Status{vehiclestore.StatusMaintenance, vehiclestore.StatusRetired}},
+		}
+
+		vehicles, _, err = store.FindClosestFrom(ctx, location, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{middle}, withoutDirections(vehicles))
+
+		vehicles, _, err = store.FindInBoundingBox(ctx, box, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{middle}, vehicles)
+
+		// The transition updated middle after the creation of the others.
+		opts = vehiclestore.ListOptions{
+			Filter: vehiclestore.Filter{UpdatedSince: &middle.UpdatedAt},
+		}
+
+		vehicles, _, err = store.FindClosestFrom(ctx, location, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{middle}, withoutDirections(vehicles))
+
+		vehicles, _, err = store.FindInBoundingBox(ctx, box, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{middle}, vehicles)
+
+		middle.Type = vehiclestore.TypeMoped
+		middle, err = store.Update(ctx, middle)
+		require.NoError(t, err)
+
+		opts = vehiclestore.ListOptions{
+			Filter: vehiclestore.Filter{Types: []vehiclestore.Type{vehiclestore.TypeBike, vehiclestore.TypeMoped}},
+		}
+
+		vehicles, _, err = store.FindClosestFrom(ctx, location, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{middle}, withoutDirections(vehicles))
+
+		vehicles, _, err = store.FindInBoundingBox(ctx, box, opts)
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{middle}, vehicles)
+	})
+
+	t.Run("finds the vehicles within a radius", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		// About 130km and 260km away from (50, 50).
+		far := create(t, store, "ccc", 52, 52, 60)
+		closest := create(t, store, "aaa", 50, 50, 40)
+		middle := create(t, store, "bbb", 51, 51, 50)
+
+		vehicles, _, err := store.FindWithinRadius(ctx, vehiclestore.Point{Latitude: 50, Longitude: 50}, 150_000, vehiclestore.ListOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{closest, middle}, withoutDirections(vehicles))
+
+		vehicles, _, err = store.FindWithinRadius(ctx, vehiclestore.Point{Latitude: 50, Longitude: 50}, 300_000, vehiclestore.ListOptions{Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{closest, middle}, withoutDirections(vehicles))
+
+		vehicles, _, err = store.FindWithinRadius(ctx, vehiclestore.Point{Latitude: 53, Longitude: 53}, 150_000, vehiclestore.ListOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{far}, withoutDirections(vehicles))
+
+		vehicles, _, err = store.FindWithinRadius(ctx, vehiclestore.Point{Latitude: 0, Longitude: 0}, 1_000, vehiclestore.ListOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, vehicles)
+	})
+
+	t.Run("finds the vehicles inside an area", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		first := create(t, store, "aaa", 50, 50, 40)
+		second := create(t, store, "bbb", 51, 51, 50)
+		create(t, store, "ccc", 52, 52, 60)
+
+		box := vehiclestore.BoundingBox{
+			Min: vehiclestore.Point{Latitude: 49.5, Longitude: 49.5},
+			Max: vehiclestore.Point{Latitude: 51.5, Longitude: 51.5},
+		}
+
+		vehicles, _, err := store.FindInBoundingBox(ctx, box, vehiclestore.ListOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{first, second}, vehicles)
+
+		vehicles, _, err = store.FindInBoundingBox(ctx, box, vehiclestore.ListOptions{Limit: 1})
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{first}, vehicles)
+
+		// A quadrilateral around the first two vehicles.
+		polygon := vehiclestore.Polygon{
+			{
+				{Latitude: 49, Longitude: 49},
+				{Latitude: 49, Longitude: 52},
+				{Latitude: 51.5, Longitude: 51.8},
+				{Latitude: 52.5, Longitude: 49},
+				{Latitude: 49, Longitude: 49},
+			},
+		}
+
+		vehicles, _, err = store.FindInPolygon(ctx, polygon, vehiclestore.ListOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{first, second}, vehicles)
+
+		// Punch a hole around the second one.
+		polygon = append(polygon, []vehiclestore.Point{
+			{Latitude: 50.9, Longitude: 50.9},
+			{Latitude: 50.9, Longitude: 51.1},
+			{Latitude: 51.1, Longitude: 51.1},
+			{Latitude: 51.1, Longitude: 50.9},
+			{Latitude: 50.9, Longitude: 50.9},
+		})
+
+		vehicles, _, err = store.FindInPolygon(ctx, polygon, vehiclestore.ListOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []vehiclestore.Vehicle{first}, vehicles)
+	})
+
+	t.Run("deletes vehicles", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		v := create(t, store, "aaa", 50, 50, 40)
+
+		deleted, err := store.Delete(ctx, v.ID)
+		require.NoError(t, err)
+		assert.True(t, deleted)
+
+		deleted, err = store.Delete(ctx, v.ID)
+		require.NoError(t, err)
+		assert.False(t, deleted)
+
+		_, err = store.Get(ctx, v.ID)
+		assert.ErrorIs(t, err, vehiclestore.ErrNotFound)
+
+		// The reserved vehicles and the vehicles in use stay until they are released.
+		reserved := create(t, store, "ccc", 50, 50, 40)
+
+		_, err = store.Transition(ctx, reserved.ID, vehiclestore.StatusReserved)
+		require.NoError(t, err)
+
+		deleted, err = store.Delete(ctx, reserved.ID)
+		assert.ErrorIs(t, err, vehiclestore.ErrReserved)
+		assert.False(t, deleted)
+
+		inUse := create(t, store, "bbb", 50, 50, 40)
+
+		_, err = store.Transition(ctx, inUse.ID, vehiclestore.StatusInUse)
+		require.NoError(t, err)
+
+		deleted, err = store.Delete(ctx, inUse.ID)
+		assert.ErrorIs(t, err, vehiclestore.ErrInUse)
+		assert.False(t, deleted)
+
+		for _, id := range []int64{reserved.ID, inUse.ID} {
+			_, err = store.Transition(ctx, id, vehiclestore.StatusAvailable)
+			require.NoError(t, err)
+
+			deleted, err = store.Delete(ctx, id)
+			require.NoError(t, err)
+			assert.True(t, deleted)
+		}
+
+		vehicles, _, err := store.FindClosestFrom(ctx, vehiclestore.Point{Latitude: 50, Longitude: 50}, vehiclestore.ListOptions{Limit: 10})
+		require.NoError(t, err)
+		assert.Empty(t, vehicles)
+	})
+
+	t.Run("restores and purges deleted vehicles", func(t *testing.T) {
+		var (
+			ctx   = context.Background()
+			store = newStore(t)
+		)
+
+		v := create(t, store, "aaa", 50, 50, 40)
+		other := create(t, store, "bbb", 51, 51, 50)
+
+		_, err := store.Restore(ctx, v.ID)
+		assert.ErrorIs(t, err, vehiclestore.ErrNotFound)
+
+		for _, id := range []int64{v.ID, other.ID} {
+			deleted, err := store.Delete(ctx, id)
+			require.NoError(t, err)
+			require.True(t, deleted)
+		}
+
+		// The deleted vehicles keep their shortcode.
+		_, err = store.Create(ctx, vehiclestore.Vehicle{ShortCode: "aaa", BatteryLevel: 10})
+		assert.ErrorIs(t, err, vehiclestore.ErrDuplicateShortCode)
+
+		_, err = store.GetByShortCode(ctx, "aaa")
+		assert.ErrorIs(t, err, vehiclestore.ErrNotFound)
+
+		restored, err := store.Restore(ctx, v.ID)
+		require.NoError(t, err)
+		assert.True(t, restored.UpdatedAt.After(v.UpdatedAt))
+
+		v.UpdatedAt = restored.UpdatedAt
+		assert.Equal(t, v, restored)
+
+		got, err := store.Get(ctx, v.ID)
+		require.NoError(t, err)
+		assert.Equal(t, v, got)
+
+		// Only the vehicles deleted before the retention are purged.
+		purged, err := store.Purge(ctx, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, purged)
+
+		purged, err = store.Purge(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		_, err = store.Restore(ctx, other.ID)
+		assert.ErrorIs(t, err, vehiclestore.ErrNotFound)
+
+		create(t, store, "bbb", 51, 51, 50)
+	})
+}
+
+func create(t *testing.T, store vehiclestore.Store, shortCode string, lat, lon float64, battery int64) vehiclestore.Vehicle {
+	t.Helper()
+
+	v, err := store.Create(
+		context.Background(),
+		vehiclestore.Vehicle{
+			ShortCode:    shortCode,
+			BatteryLevel: battery,
+			Position:     vehiclestore.Point{Latitude: lat, Longitude: lon},
+		},
+	)
+	require.NoError(t, err)
+
+	return v
+}
+
+// withoutDirections clears the distance and bearing set by the nearest-first searches,
+// for the vehicles to compare equal to the created ones.
+func withoutDirections(vehicles []vehiclestore.Vehicle) []vehiclestore.Vehicle {
+	for i := range vehicles {
+		vehicles[i].Distance = nil
+		vehicles[i].Bearing = nil
+	}
+
+	return vehicles
+}
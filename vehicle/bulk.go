@@ -0,0 +1,222 @@
+package vehicle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/pkg/shortcode"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"go.uber.org/zap"
+)
+
+// maxBulkCreateItems bounds the number of vehicles created by a single request.
+const maxBulkCreateItems = 1000
+
+// errTooManyBulkCreateItems stops reading the requests having more than maxBulkCreateItems.
+var errTooManyBulkCreateItems = errors.New("too many bulk creation items")
+
+// errBulkCreateAborted rolls back the all-or-nothing bulk creations having a failed item.
+var errBulkCreateAborted = errors.New("bulk creation aborted")
+
+// BulkCreateResult is the outcome of the creation of an item of the request,
+// either the created vehicle or the error.
+type BulkCreateResult struct {
+	// Index of the item in the request, starting at 0.
+	Index   int                `json:"index"`
+	Vehicle *Vehicle           `json:"vehicle,omitempty"`
+	Error   *httputil.APIError `json:"error,omitempty"`
+}
+
+func newBulkCreateError(index int, err error) BulkCreateResult {
+	var apiError *httputil.APIError
+	if !errors.As(err, &apiError) {
+		apiError = &httputil.APIError{Code: httputil.ErrCodeInternalServerError, Message: "Unexpected error"}
+	}
+
+	return BulkCreateResult{Index: index, Error: apiError}
+}
+
+type BulkCreateResponse struct {
+	Results []BulkCreateResult `json:"results"`
+}
+
+// BulkCreateHandler creates many vehicles from a JSON array or a NDJSON stream of CreateRequest.
+// By default every item is created on its own, the atomic query parameter creates
+// either all of them in a single transaction, or none.
+type BulkCreateHandler struct {
+	store      storage.Store
+	shortCodes *shortcode.Generator
+	logger     *zap.Logger
+}
+
+func NewBulkCreateHandler(store storage.Store, shortCodes *shortcode.Generator, logger *zap.Logger) *BulkCreateHandler {
+	return &BulkCreateHandler{
+		store:      store,
+		shortCodes: shortCodes,
+		logger:     logger.With(zap.String("handler", "bulk_create_vehicles")),
+	}
+}
+
+func (b *BulkCreateHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	atomic := false
+	if r.URL.Query().Has("atomic") {
+		var err error
+
+		if atomic, err = strconv.ParseBool(r.URL.Query().Get("atomic")); err != nil {
+			httputil.ServeError(rw, http.StatusBadRequest, newValidationError([]string{"atomic must be a boolean"}))
+			return
+		}
+	}
+
+	items, err := decodeBulkCreateItems(r)
+	if errors.Is(err, errTooManyBulkCreateItems) {
+		httputil.ServeError(
+			rw,
+			http.StatusBadRequest,
+			newValidationError([]string{fmt.Sprintf("at most %d vehicles can be created at once", maxBulkCreateItems)}),
+		)
+		return
+	}
+	if err != nil {
+		b.logger.Error(
+			"Could not decode request body",
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	if atomic {
+		b.createAll(rw, r, items)
+		return
+	}
+
+	results := make([]BulkCreateResult, len(items))
+	for i, item := range items {
+		results[i] = b.createItem(r, b.store, i, item)
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, &BulkCreateResponse{Results: results})
+}
+
+// createAll creates every item in a single transaction, rolled back if any of them fails.
+// The failures are then the only results, served with the highest of their status codes.
+func (b *BulkCreateHandler) createAll(rw http.ResponseWriter, r *http.Request, items []json.RawMessage) {
+	var (
+		results    []BulkCreateResult
+		failures   []BulkCreateResult
+		statusCode = http.StatusCreated
+	)
+
+	err := b.store.WithTx(r.Context(), func(tx storage.Store) error {
+		results = make([]BulkCreateResult, len(items))
+
+		// Every item is tried to report all the failures, each one is created in its own savepoint.
+		for i, item := range items {
+			results[i] = b.createItem(r, tx, i, item)
+
+			if results[i].Error != nil {
+				failures = append(failures, results[i])
+				statusCode = max(statusCode, bulkCreateErrorStatus(results[i].Error))
+			}
+		}
+
+		if len(failures) > 0 {
+			return errBulkCreateAborted
+		}
+
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errBulkCreateAborted):
+		httputil.ServeJSON(rw, statusCode, &BulkCreateResponse{Results: failures})
+	case err != nil:
+		b.logger.Error(
+			"Could not save the new vehicles",
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+	default:
+		httputil.ServeJSON(rw, http.StatusCreated, &BulkCreateResponse{Results: results})
+	}
+}
+
+func (b *BulkCreateHandler) createItem(r *http.Request, store storage.Store, index int, item json.RawMessage) BulkCreateResult {
+	var req CreateRequest
+
+	if item == nil {
+		return newBulkCreateError(index, newValidationError([]string{
+			fmt.Sprintf("line must have at most %d bytes", httputil.MaxNDJSONLineSize),
+		}))
+	}
+
+	if err := json.Unmarshal(item, &req); err != nil {
+		return newBulkCreateError(index, newValidationError([]string{"invalid vehicle"}))
+	}
+
+	newVehicle, err := create(r.Context(), store, b.shortCodes, req)
+	if err != nil {
+		if createErrorStatus(err) == http.StatusInternalServerError {
+			b.logger.Error(
+				"Could not save the new vehicle",
+				zap.Int("index", index),
+				zap.Error(err),
+			)
+		}
+		return newBulkCreateError(index, err)
+	}
+
+	created := newVehicleFromModel(newVehicle)
+
+	return BulkCreateResult{Index: index, Vehicle: &created}
+}
+
+// bulkCreateErrorStatus returns the HTTP status code of a failed item.
+func bulkCreateErrorStatus(err *httputil.APIError) int {
+	if err.Code == httputil.ErrCodeInternalServerError {
+		return http.StatusInternalServerError
+	}
+
+	return createErrorStatus(err)
+}
+
+// decodeBulkCreateItems returns the raw items of a NDJSON stream, or of a JSON array.
+// The NDJSON lines too long to be read are nil items.
+// It stops reading with errTooManyBulkCreateItems past maxBulkCreateItems.
+func decodeBulkCreateItems(r *http.Request) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+
+	keep := func(raw []byte) error {
+		if len(items) == maxBulkCreateItems {
+			return errTooManyBulkCreateItems
+		}
+
+		if raw != nil {
+			raw = append(json.RawMessage(nil), raw...)
+		}
+
+		items = append(items, raw)
+
+		return nil
+	}
+
+	if !strings.EqualFold(r.Header.Get("Content-Type"), "application/x-ndjson") {
+		err := httputil.DecodeRequestAsJSONArray(r, func(_ int, raw json.RawMessage) error {
+			return keep(raw)
+		})
+		return items, err
+	}
+
+	err := httputil.DecodeRequestAsNDJSON(r, func(_ int, raw []byte, _ error) error {
+		// The only error is httputil.ErrNDJSONLineTooLong, raw is then nil.
+		return keep(raw)
+	})
+
+	return items, err
+}
@@ -0,0 +1,176 @@
+//go:build !integration
+
+package vehicle_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/vehicle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBulkCreateHandler(t *testing.T) {
+	for _, testCase := range []struct {
+		desc         string
+		query        string
+		contentType  string
+		body         string
+		wantStatus   int
+		wantBody     string
+		wantVehicles int
+	}{
+		{
+			desc:        "array",
+			contentType: "application/json",
+			body: `[
+				{"shortcode":"abcd","latitude":12.5,"longitude":3.2,"battery":42},
+				{"latitude":12.6,"longitude":3.3,"battery":43},
+				{"shortcode":"toolong","latitude":12.7,"longitude":3.4,"battery":420},
+				{"shortcode":"efgh","latitude":12.7,"longitude":3.4,"battery":44},
+				{"battery":"full"}
+			]`,
+			wantStatus: http.StatusOK,
+			wantBody: `{"results":[
+				{"index":0,"vehicle":{"id":2,"shortcode":"abcd","battery":42,"latitude":12.5,"longitude":3.2,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}},
+				{"index":1,"vehicle":{"id":3,"shortcode":"BBBB","battery":43,"latitude":12.6,"longitude":3.3,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}},
+				{"index":2,"error":{"code":1003,"message":"The request payload is invalid","details":["short code too long","battery level must be > 0 and <= 100"]}},
+				{"index":3,"error":{"code":1005,"message":"The shortcode is already used by another vehicle","details":{"shortcode":"efgh"}}},
+				{"index":4,"error":{"code":1003,"message":"The request payload is invalid","details":["invalid vehicle"]}}
+			]}`,
+			wantVehicles: 3,
+		},
+		{
+			desc:        "NDJSON",
+			contentType: "application/x-ndjson",
+			body: `{"shortcode":"abcd","latitude":12.5,"longitude":3.2,"battery":42}
+
+{"shortcode":"efgh","latitude":12.7,"longitude":3.4,"battery":44}`,
+			wantStatus: http.StatusOK,
+			wantBody: `{"results":[
+				{"index":0,"vehicle":{"id":2,"shortcode":"abcd","battery":42,"latitude":12.5,"longitude":3.2,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}},
+				{"index":1,"error":{"code":1005,"message":"The shortcode is already used by another vehicle","details":{"shortcode":"efgh"}}}
+			]}`,
+			wantVehicles: 2,
+		},
+		{
+			desc:        "all or nothing",
+			query:       "atomic=true",
+			contentType: "application/json",
+			body: `[
+				{"shortcode":"abcd","latitude":12.5,"longitude":3.2,"battery":42},
+				{"shortcode":"ijkl","latitude":12.7,"longitude":3.4,"battery":44}
+			]`,
+			wantStatus: http.StatusCreated,
+			wantBody: `{"results":[
+				{"index":0,"vehicle":{"id":2,"shortcode":"abcd","battery":42,"latitude":12.5,"longitude":3.2,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}},
+				{"index":1,"vehicle":{"id":3,"shortcode":"ijkl","battery":44,"latitude":12.7,"longitude":3.4,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}}
+			]}`,
+			wantVehicles: 3,
+		},
+		{
+			desc:        "all or nothing with failures",
+			query:       "atomic=true",
+			contentType: "application/json",
+			body: `[
+				{"shortcode":"abcd","latitude":12.5,"longitude":3.2,"battery":42},
+				{"shortcode":"efgh","latitude":12.7,"longitude":3.4,"battery":44},
+				{"shortcode":"ijkl","latitude":12.7,"longitude":3.4,"battery":420}
+			]`,
+			wantStatus: http.StatusConflict,
+			wantBody: `{"results":[
+				{"index":1,"error":{"code":1005,"message":"The shortcode is already used by another vehicle","details":{"shortcode":"efgh"}}},
+				{"index":2,"error":{"code":1003,"message":"The request payload is invalid","details":["battery level must be > 0 and <= 100"]}}
+			]}`,
+			wantVehicles: 1,
+		},
+		{
+			desc:         "invalid mode",
+			query:        "atomic=maybe",
+			contentType:  "application/json",
+			body:         `[]`,
+			wantStatus:   http.StatusBadRequest,
+			wantBody:     `{"code":1003,"message":"The request payload is invalid","details":["atomic must be a boolean"]}`,
+			wantVehicles: 1,
+		},
+		{
+			desc:         "too many items in an array",
+			contentType:  "application/json",
+			body:         "[" + strings.Repeat(`{},`, 1001) + "never read",
+			wantStatus:   http.StatusBadRequest,
+			wantBody:     `{"code":1003,"message":"The request payload is invalid","details":["at most 1000 vehicles can be created at once"]}`,
+			wantVehicles: 1,
+		},
+		{
+			desc:         "too many items in a stream",
+			contentType:  "application/x-ndjson",
+			body:         strings.Repeat("{}\n", 1001) + "never read",
+			wantStatus:   http.StatusBadRequest,
+			wantBody:     `{"code":1003,"message":"The request payload is invalid","details":["at most 1000 vehicles can be created at once"]}`,
+			wantVehicles: 1,
+		},
+		{
+			desc:        "too long line in a stream",
+			contentType: "application/x-ndjson",
+			body: `{"shortcode":"abcd","latitude":12.5,"longitude":3.2,"battery":42,"note":"` + strings.Repeat("a", 1<<20) + `"}
+{"shortcode":"ijkl","latitude":12.7,"longitude":3.4,"battery":44}`,
+			wantStatus: http.StatusOK,
+			wantBody: `{"results":[
+				{"index":0,"error":{"code":1003,"message":"The request payload is invalid","details":["line must have at most 1048576 bytes"]}},
+				{"index":1,"vehicle":{"id":2,"shortcode":"ijkl","battery":44,"latitude":12.7,"longitude":3.4,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}}
+			]}`,
+			wantVehicles: 2,
+		},
+		{
+			desc:         "trailing garbage",
+			contentType:  "application/json",
+			body:         `[] []`,
+			wantStatus:   http.StatusBadRequest,
+			wantBody:     `{"code":2,"message":"Unexpected garbage at the end on the request body"}`,
+			wantVehicles: 1,
+		},
+		{
+			desc:         "not an array",
+			contentType:  "application/json",
+			body:         `{"shortcode":"abcd"}`,
+			wantStatus:   http.StatusBadRequest,
+			wantBody:     `{"code":1,"message":"Unexpected error"}`,
+			wantVehicles: 1,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			store := newMemoryStore()
+
+			_, err := store.Vehicle().Create(
+				context.Background(),
+				vehiclestore.Vehicle{ShortCode: "efgh", BatteryLevel: 42, Position: vehiclestore.Point{Latitude: 12.5, Longitude: 3.2}},
+			)
+			require.NoError(t, err)
+
+			handler := vehicle.NewBulkCreateHandler(store, newShortCodeGenerator(t, "AB", []string{"A"}), zap.NewNop())
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/vehicles/bulk?"+testCase.query, strings.NewReader(testCase.body))
+			req.Header.Add("Content-Type", testCase.contentType)
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+
+			vehicles, _, err := store.Vehicle().FindInBoundingBox(
+				context.Background(),
+				vehiclestore.BoundingBox{Min: vehiclestore.Point{Latitude: -90, Longitude: -180}, Max: vehiclestore.Point{Latitude: 90, Longitude: 180}},
+				vehiclestore.ListOptions{},
+			)
+			require.NoError(t, err)
+			assert.Len(t, vehicles, testCase.wantVehicles)
+		})
+	}
+}
@@ -1,29 +1,74 @@
 package vehicle
 
 import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/pkg/shortcode"
 	"github.com/Cirederf1/vehicle-server/storage"
 	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
 	"go.uber.org/zap"
 )
 
+// MaxShortCodeLength is the length of the longest shortcodes, and of the generated ones.
+const MaxShortCodeLength = 4
+
+// maxShortCodeAttempts bounds the generated shortcodes already used by other vehicles.
+const maxShortCodeAttempts = 5
+
+// CreateRequest is the representation of a vehicle sent by the clients,
+// the server generates the shortcode when it is omitted on creation.
 type CreateRequest struct {
 	Latitude     float64 `json:"latitude"`
 	Longitude    float64 `json:"longitude"`
 	ShortCode    string  `json:"shortcode"`
 	BatteryLevel int64   `json:"battery"`
+	// Type defaults to a scooter, the attributes below are validated according to it.
+	Type      string `json:"type,omitempty"`
+	HelmetBox *bool  `json:"helmet_box,omitempty"`
+	Seats     *int64 `json:"seats,omitempty"`
 }
 
-func (f *CreateRequest) validate() []string {
-	var validationIssues []string
+func newCreateRequestFromModel(v vehiclestore.Vehicle) CreateRequest {
+	return CreateRequest{
+		Latitude:     v.Position.Latitude,
+		Longitude:    v.Position.Longitude,
+		ShortCode:    v.ShortCode,
+		BatteryLevel: v.BatteryLevel,
+		Type:         string(v.Type),
+		HelmetBox:    v.Attributes.HelmetBox,
+		Seats:        v.Attributes.Seats,
+	}
+}
 
-	if f.ShortCode == "" {
-		validationIssues = append(validationIssues, "missing short code")
+// model returns the vehicle described by the request.
+func (f *CreateRequest) model() vehiclestore.Vehicle {
+	return vehiclestore.Vehicle{
+		ShortCode:    f.ShortCode,
+		BatteryLevel: f.BatteryLevel,
+		Position: vehiclestore.Point{
+			Latitude:  f.Latitude,
+			Longitude: f.Longitude,
+		},
+		Type: vehiclestore.Type(f.Type),
+		Attributes: vehiclestore.Attributes{
+			HelmetBox: f.HelmetBox,
+			Seats:     f.Seats,
+		},
 	}
+}
 
-	if len(f.ShortCode) > 4 {
+func (f *CreateRequest) validate() []string {
+	var validationIssues []string
+
+	// The generated shortcodes may use multi-byte characters.
+	if utf8.RuneCountInString(f.ShortCode) > MaxShortCodeLength {
 		validationIssues = append(validationIssues, "short code too long")
 	}
 
@@ -31,15 +76,74 @@ func (f *CreateRequest) validate() []string {
 		validationIssues = append(validationIssues, "latitude must be >= -90 and <= 90")
 	}
 
-	if f.Longitude < -90 || f.Longitude > 90 {
-		validationIssues = append(validationIssues, "longitude must be >= -90 and <= 90")
+	if f.Longitude < -180 || f.Longitude > 180 {
+		validationIssues = append(validationIssues, "longitude must be >= -180 and <= 180")
 	}
 
 	if f.BatteryLevel < 0 || f.BatteryLevel > 100 {
 		validationIssues = append(validationIssues, "battery level must be > 0 and <= 100")
 	}
 
-	return validationIssues
+	validateType, ok := typeRules[vehiclestore.Type(f.Type)]
+	if !ok {
+		return append(validationIssues, "type must be one of "+joinTypes(vehiclestore.Types))
+	}
+
+	return append(validationIssues, validateType(f)...)
+}
+
+// typeRules holds the validation rules specific to each vehicle type,
+// on top of the ones shared by every vehicle.
+var typeRules = map[vehiclestore.Type]func(*CreateRequest) []string{
+	vehiclestore.TypeScooter: func(f *CreateRequest) []string {
+		var validationIssues []string
+
+		if f.HelmetBox != nil {
+			validationIssues = append(validationIssues, "helmet_box is only available on mopeds")
+		}
+
+		if f.Seats != nil && *f.Seats != 1 {
+			validationIssues = append(validationIssues, "seats must be 1 for a scooter")
+		}
+
+		return validationIssues
+	},
+	vehiclestore.TypeBike: func(f *CreateRequest) []string {
+		var validationIssues []string
+
+		if f.HelmetBox != nil {
+			validationIssues = append(validationIssues, "helmet_box is only available on mopeds")
+		}
+
+		if f.Seats != nil && (*f.Seats < 1 || *f.Seats > 2) {
+			validationIssues = append(validationIssues, "seats must be >= 1 and <= 2 for a bike")
+		}
+
+		return validationIssues
+	},
+	vehiclestore.TypeMoped: func(f *CreateRequest) []string {
+		var validationIssues []string
+
+		// The riders must know whether they need to bring their own helmet.
+		if f.HelmetBox == nil {
+			validationIssues = append(validationIssues, "missing helmet_box for a moped")
+		}
+
+		if f.Seats != nil && (*f.Seats < 1 || *f.Seats > 2) {
+			validationIssues = append(validationIssues, "seats must be >= 1 and <= 2 for a moped")
+		}
+
+		return validationIssues
+	},
+}
+
+func joinTypes(types []vehiclestore.Type) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+
+	return strings.Join(names, ", ")
 }
 
 type CreateResponse struct {
@@ -47,14 +151,16 @@ type CreateResponse struct {
 }
 
 type CreateHandler struct {
-	store  storage.Store
-	logger *zap.Logger
+	store      storage.Store
+	shortCodes *shortcode.Generator
+	logger     *zap.Logger
 }
 
-func NewCreateHandler(store storage.Store, logger *zap.Logger) *CreateHandler {
+func NewCreateHandler(store storage.Store, shortCodes *shortcode.Generator, logger *zap.Logger) *CreateHandler {
 	return &CreateHandler{
-		store:  store,
-		logger: logger.With(zap.String("handler", "create_vehicle")),
+		store:      store,
+		shortCodes: shortCodes,
+		logger:     logger.With(zap.String("handler", "create_vehicle")),
 	}
 }
 
@@ -70,32 +176,16 @@ func (c *CreateHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if validationIssues := req.validate(); len(validationIssues) > 0 {
-		httputil.ServeError(
-			rw,
-			http.StatusBadRequest,
-			newValidationError(validationIssues),
-		)
-		return
-	}
-
-	newVehicle, err := c.store.Vehicle().Create(
-		r.Context(),
-		vehiclestore.Vehicle{
-			ShortCode:    req.ShortCode,
-			BatteryLevel: req.BatteryLevel,
-			Position: vehiclestore.Point{
-				Latitude:  req.Latitude,
-				Longitude: req.Longitude,
-			},
-		},
-	)
+	newVehicle, err := create(r.Context(), c.store, c.shortCodes, req)
 	if err != nil {
-		c.logger.Error(
-			"Could not save the new vehicle",
-			zap.Error(err),
-		)
-		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		statusCode := createErrorStatus(err)
+		if statusCode == http.StatusInternalServerError {
+			c.logger.Error(
+				"Could not save the new vehicle",
+				zap.Error(err),
+			)
+		}
+		httputil.ServeError(rw, statusCode, err)
 		return
 	}
 
@@ -105,3 +195,87 @@ func (c *CreateHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		&CreateResponse{Vehicle: newVehicleFromModel(newVehicle)},
 	)
 }
+
+// create validates req and creates the vehicle, drawing its shortcode when it is omitted.
+// The validation issues, the shortcode conflicts and the exhausted shortcode draws
+// are returned as API errors, see createErrorStatus.
+func create(ctx context.Context, store storage.Store, shortCodes *shortcode.Generator, req CreateRequest) (vehiclestore.Vehicle, error) {
+	generateShortCode := req.ShortCode == ""
+	if generateShortCode {
+		if err := drawShortCode(shortCodes, &req); err != nil {
+			return vehiclestore.Vehicle{}, err
+		}
+	}
+
+	req.Type = cmp.Or(req.Type, string(vehiclestore.TypeScooter))
+
+	if validationIssues := req.validate(); len(validationIssues) > 0 {
+		return vehiclestore.Vehicle{}, newValidationError(validationIssues)
+	}
+
+	newVehicle, err := createVehicle(ctx, store, req)
+
+	// Draw another shortcode when the generated one is already used.
+	for attempt := 1; generateShortCode && errors.Is(err, vehiclestore.ErrDuplicateShortCode) && attempt < maxShortCodeAttempts; attempt++ {
+		if err = drawShortCode(shortCodes, &req); err != nil {
+			break
+		}
+
+		newVehicle, err = createVehicle(ctx, store, req)
+	}
+
+	if errors.Is(err, vehiclestore.ErrDuplicateShortCode) {
+		if generateShortCode {
+			return vehiclestore.Vehicle{}, newShortCodeExhaustedError(maxShortCodeAttempts)
+		}
+		return vehiclestore.Vehicle{}, newShortCodeConflictError(req.ShortCode)
+	}
+
+	return newVehicle, err
+}
+
+// createErrorStatus returns the HTTP status code of an error returned by create.
+func createErrorStatus(err error) int {
+	var apiError *httputil.APIError
+
+	switch {
+	case !errors.As(err, &apiError):
+		return http.StatusInternalServerError
+	case apiError.Code == httputil.ErrCodeResourceConflict:
+		return http.StatusConflict
+	case apiError.Code == httputil.ErrCodeResourceExhausted:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// drawShortCode sets a generated shortcode on req.
+func drawShortCode(shortCodes *shortcode.Generator, req *CreateRequest) error {
+	shortCode, err := shortCodes.Generate()
+	if err != nil {
+		return fmt.Errorf("could not generate a shortcode: %w", err)
+	}
+
+	req.ShortCode = shortCode
+
+	return nil
+}
+
+// createVehicle creates a vehicle along with its first telemetry record.
+func createVehicle(ctx context.Context, store storage.Store, req CreateRequest) (vehiclestore.Vehicle, error) {
+	var created vehiclestore.Vehicle
+
+	err := store.WithTx(ctx, func(tx storage.Store) error {
+		var err error
+
+		created, err = tx.Vehicle().Create(ctx, req.model())
+		if err != nil {
+			return err
+		}
+
+		return tx.Telemetry().Append(ctx, newRecordFromModel(created))
+	})
+
+	return created, err
+}
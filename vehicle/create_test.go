@@ -5,13 +5,15 @@ package vehicle_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/pkg/shortcode"
 	"github.com/Cirederf1/vehicle-server/pkg/testutil"
-	"github.com/Cirederf1/vehicle-server/storage"
 	"github.com/Cirederf1/vehicle-server/vehicle"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -22,18 +24,6 @@ func TestCreateHandlerValidation(t *testing.T) {
 		wantStatus  int
 		wantPayload httputil.APIError
 	}{
-		{
-			desc:       "missing all fields",
-			vehicle:    vehicle.Vehicle{},
-			wantStatus: http.StatusBadRequest,
-			wantPayload: httputil.APIError{
-				Code:    httputil.ErrCodeInvalidRequestPayload,
-				Message: "The request payload is invalid",
-				Details: []any{
-					"missing short code",
-				},
-			},
-		},
 		{
 			desc: "short code too long",
 			vehicle: vehicle.Vehicle{
@@ -86,10 +76,10 @@ func TestCreateHandlerValidation(t *testing.T) {
 			},
 		},
 		{
-			desc: "longitude below -90",
+			desc: "longitude below -180",
 			vehicle: vehicle.Vehicle{
 				ShortCode:    "aabb",
-				Longitude:    -93.3,
+				Longitude:    -183.3,
 				Latitude:     23.4,
 				BatteryLevel: 34,
 			},
@@ -98,15 +88,15 @@ func TestCreateHandlerValidation(t *testing.T) {
 				Code:    httputil.ErrCodeInvalidRequestPayload,
 				Message: "The request payload is invalid",
 				Details: []any{
-					"longitude must be >= -90 and <= 90",
+					"longitude must be >= -180 and <= 180",
 				},
 			},
 		},
 		{
-			desc: "longitude above 90",
+			desc: "longitude above 180",
 			vehicle: vehicle.Vehicle{
 				ShortCode:    "aabb",
-				Longitude:    94.3,
+				Longitude:    184.3,
 				Latitude:     23.4,
 				BatteryLevel: 34,
 			},
@@ -115,7 +105,7 @@ func TestCreateHandlerValidation(t *testing.T) {
 				Code:    httputil.ErrCodeInvalidRequestPayload,
 				Message: "The request payload is invalid",
 				Details: []any{
-					"longitude must be >= -90 and <= 90",
+					"longitude must be >= -180 and <= 180",
 				},
 			},
 		},
@@ -156,7 +146,8 @@ func TestCreateHandlerValidation(t *testing.T) {
 	} {
 		t.Run(testCase.desc, func(t *testing.T) {
 			handler := vehicle.NewCreateHandler(
-				storage.NewMemoryStore(),
+				newMemoryStore(),
+				newShortCodeGenerator(t, shortcode.DefaultAlphabet, nil),
 				zap.NewNop(),
 			)
 
@@ -178,3 +169,153 @@ func TestCreateHandlerValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateHandlerConflict(t *testing.T) {
+	handler := vehicle.NewCreateHandler(
+		newMemoryStore(),
+		newShortCodeGenerator(t, shortcode.DefaultAlphabet, nil),
+		zap.NewNop(),
+	)
+
+	create := func() *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/vehicles",
+			testutil.EncodeJSON(t, vehicle.Vehicle{ShortCode: "aabb", Latitude: 23.4, Longitude: 44.3, BatteryLevel: 34}),
+		)
+		req.Header.Add("Content-Type", "application/json")
+
+		handler.ServeHTTP(resp, req)
+
+		return resp
+	}
+
+	assert.Equal(t, http.StatusCreated, create().Result().StatusCode)
+
+	resp := create()
+	assert.Equal(t, http.StatusConflict, resp.Result().StatusCode)
+	assert.JSONEq(
+		t,
+		`{"code":1005,"message":"The shortcode is already used by another vehicle","details":{"shortcode":"aabb"}}`,
+		resp.Body.String(),
+	)
+}
+
+func TestCreateHandlerGeneratesShortCode(t *testing.T) {
+	// Only BBBB can be generated.
+	handler := vehicle.NewCreateHandler(
+		newMemoryStore(),
+		newShortCodeGenerator(t, "AB", []string{"A"}),
+		zap.NewNop(),
+	)
+
+	create := func() *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/vehicles",
+			strings.NewReader(`{"latitude":23.4,"longitude":44.3,"battery":34}`),
+		)
+		req.Header.Add("Content-Type", "application/json")
+
+		handler.ServeHTTP(resp, req)
+
+		return resp
+	}
+
+	resp := create()
+	assert.Equal(t, http.StatusCreated, resp.Result().StatusCode)
+	assert.JSONEq(
+		t,
+		`{"vehicle":{"id":1,"shortcode":"BBBB","battery":34,"latitude":23.4,"longitude":44.3,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}}`,
+		resp.Body.String(),
+	)
+
+	// Every generated shortcode is already used.
+	resp = create()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Result().StatusCode)
+	assert.JSONEq(
+		t,
+		`{"code":1007,"message":"Could not allocate a shortcode, retry or provide one","details":{"attempts":5}}`,
+		resp.Body.String(),
+	)
+}
+
+func newShortCodeGenerator(t *testing.T, alphabet string, denyList []string) *shortcode.Generator {
+	t.Helper()
+
+	generator, err := shortcode.NewGenerator(alphabet, vehicle.MaxShortCodeLength, denyList)
+	require.NoError(t, err)
+
+	return generator
+}
+
+func TestCreateHandlerTypes(t *testing.T) {
+	for _, testCase := range []struct {
+		desc       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "moped with its attributes",
+			body:       `{"shortcode":"aabb","latitude":23.4,"longitude":44.3,"battery":34,"type":"moped","helmet_box":true,"seats":2}`,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"vehicle":{"id":1,"shortcode":"aabb","battery":34,"latitude":23.4,"longitude":44.3,"type":"moped","helmet_box":true,"seats":2,"status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}}`,
+		},
+		{
+			desc:       "bike with two seats",
+			body:       `{"shortcode":"aabb","latitude":23.4,"longitude":44.3,"battery":34,"type":"bike","seats":2}`,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"vehicle":{"id":1,"shortcode":"aabb","battery":34,"latitude":23.4,"longitude":44.3,"type":"bike","seats":2,"status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}}`,
+		},
+		{
+			desc:       "shortcode of multi-byte characters",
+			body:       `{"shortcode":"ÄÖÜÉ","latitude":23.4,"longitude":44.3,"battery":34}`,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"vehicle":{"id":1,"shortcode":"ÄÖÜÉ","battery":34,"latitude":23.4,"longitude":44.3,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}}`,
+		},
+		{
+			desc:       "unknown type",
+			body:       `{"shortcode":"aabb","latitude":23.4,"longitude":44.3,"battery":34,"type":"car"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["type must be one of scooter, bike, moped"]}`,
+		},
+		{
+			desc:       "scooter with a helmet box and two seats",
+			body:       `{"shortcode":"aabb","latitude":23.4,"longitude":44.3,"battery":34,"helmet_box":false,"seats":2}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["helmet_box is only available on mopeds","seats must be 1 for a scooter"]}`,
+		},
+		{
+			desc:       "bike with too many seats",
+			body:       `{"shortcode":"aabb","latitude":23.4,"longitude":44.3,"battery":34,"type":"bike","seats":3}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["seats must be >= 1 and <= 2 for a bike"]}`,
+		},
+		{
+			desc:       "moped without helmet box",
+			body:       `{"shortcode":"aabb","latitude":23.4,"longitude":44.3,"battery":34,"type":"moped","seats":0}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["missing helmet_box for a moped","seats must be >= 1 and <= 2 for a moped"]}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			handler := vehicle.NewCreateHandler(
+				newMemoryStore(),
+				newShortCodeGenerator(t, shortcode.DefaultAlphabet, nil),
+				zap.NewNop(),
+			)
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/vehicles", strings.NewReader(testCase.body))
+			req.Header.Add("Content-Type", "application/json")
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
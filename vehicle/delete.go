@@ -1,10 +1,12 @@
 package vehicle
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
 
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
 	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
 	"go.uber.org/zap"
 )
 
@@ -21,22 +23,32 @@ func NewDeleteHandler(store storage.Store, logger *zap.Logger) *DeleteHandler {
 }
 
 func (d *DeleteHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
-	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
-
-	if err!=nil{
-		http.Error(rw, "Invalid ID", http.StatusBadRequest)
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		httputil.ServeError(rw, http.StatusBadRequest, err)
 		return
 	}
 
-	check, err := d.store.Vehicle().Delete(r.Context(), id)
-
-	if err != nil {
-		http.Error(rw, "Test", http.StatusInternalServerError)
+	deleted, err := d.store.Vehicle().Delete(r.Context(), id)
+	switch {
+	case errors.Is(err, vehiclestore.ErrReserved):
+		httputil.ServeError(rw, http.StatusConflict, newVehicleUnavailableError(id, vehiclestore.StatusReserved))
 		return
-	}
-	if check {
-		rw.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, vehiclestore.ErrInUse):
+		httputil.ServeError(rw, http.StatusConflict, newVehicleUnavailableError(id, vehiclestore.StatusInUse))
+		return
+	case err != nil:
+		d.logger.Error(
+			"Could not delete the vehicle",
+			zap.Int64("id", id),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	case !deleted:
+		httputil.ServeError(rw, http.StatusNotFound, newNotFoundError(id))
 		return
 	}
-	rw.WriteHeader(http.StatusNotFound)
+
+	rw.WriteHeader(http.StatusNoContent)
 }
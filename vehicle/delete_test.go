@@ -0,0 +1,81 @@
+//go:build !integration
+
+package vehicle_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/vehicle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDeleteHandler(t *testing.T) {
+	for _, testCase := range []struct {
+		desc       string
+		id         string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "available vehicle",
+			id:         "1",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			desc:       "vehicle in use",
+			id:         "2",
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"code":1005,"message":"The vehicle is not available","details":{"id":2,"status":"in_use"}}`,
+		},
+		{
+			desc:       "unknown vehicle",
+			id:         "3",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":1004,"message":"The vehicle does not exist","details":{"id":3}}`,
+		},
+		{
+			desc:       "invalid ID",
+			id:         "abc",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["id must be an integer"]}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			store := newMemoryStore()
+
+			for _, shortCode := range []string{"abcd", "efgh"} {
+				_, err := store.Vehicle().Create(
+					context.Background(),
+					vehiclestore.Vehicle{
+						ShortCode:    shortCode,
+						BatteryLevel: 42,
+						Position:     vehiclestore.Point{Latitude: 12.5, Longitude: 3.2},
+					},
+				)
+				require.NoError(t, err)
+			}
+
+			_, err := store.Vehicle().Transition(context.Background(), 2, vehiclestore.StatusInUse)
+			require.NoError(t, err)
+
+			handler := vehicle.NewDeleteHandler(store, zap.NewNop())
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodDelete, "/vehicles/"+testCase.id, nil)
+			req.SetPathValue("id", testCase.id)
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			if testCase.wantBody != "" {
+				assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+			}
+		})
+	}
+}
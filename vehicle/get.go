@@ -0,0 +1,85 @@
+package vehicle
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"go.uber.org/zap"
+)
+
+type GetResponse struct {
+	Vehicle Vehicle `json:"vehicle"`
+}
+
+type GetHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewGetHandler(store storage.Store, logger *zap.Logger) *GetHandler {
+	return &GetHandler{
+		store:  store,
+		logger: logger.With(zap.String("handler", "get_vehicle")),
+	}
+}
+
+func (g *GetHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	v, err := g.store.Vehicle().Get(r.Context(), id)
+	switch {
+	case errors.Is(err, vehiclestore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newNotFoundError(id))
+		return
+	case err != nil:
+		g.logger.Error(
+			"Could not get the vehicle from store",
+			zap.Int64("id", id),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, &GetResponse{Vehicle: newVehicleFromModel(v)})
+}
+
+type GetByShortCodeHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewGetByShortCodeHandler(store storage.Store, logger *zap.Logger) *GetByShortCodeHandler {
+	return &GetByShortCodeHandler{
+		store:  store,
+		logger: logger.With(zap.String("handler", "get_vehicle_by_shortcode")),
+	}
+}
+
+func (g *GetByShortCodeHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	shortCode := r.PathValue("code")
+
+	v, err := g.store.Vehicle().GetByShortCode(r.Context(), shortCode)
+	switch {
+	case errors.Is(err, vehiclestore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newShortCodeNotFoundError(shortCode))
+		return
+	case err != nil:
+		g.logger.Error(
+			"Could not get the vehicle from store",
+			zap.String("shortcode", shortCode),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, &GetResponse{Vehicle: newVehicleFromModel(v)})
+}
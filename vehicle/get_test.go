@@ -0,0 +1,116 @@
+//go:build !integration
+
+package vehicle_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/vehicle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGetHandler(t *testing.T) {
+	store := newMemoryStore()
+
+	_, err := store.Vehicle().Create(
+		context.Background(),
+		vehiclestore.Vehicle{
+			ShortCode:    "abcd",
+			BatteryLevel: 42,
+			Position:     vehiclestore.Point{Latitude: 12.5, Longitude: 3.2},
+		},
+	)
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		desc       string
+		id         string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "existing vehicle",
+			id:         "1",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"vehicle":{"id":1,"shortcode":"abcd","battery":42,"latitude":12.5,"longitude":3.2,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}}`,
+		},
+		{
+			desc:       "unknown vehicle",
+			id:         "2",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":1004,"message":"The vehicle does not exist","details":{"id":2}}`,
+		},
+		{
+			desc:       "invalid id",
+			id:         "abc",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["id must be an integer"]}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			handler := vehicle.NewGetHandler(store, zap.NewNop())
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/vehicles/"+testCase.id, http.NoBody)
+			req.SetPathValue("id", testCase.id)
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestGetByShortCodeHandler(t *testing.T) {
+	store := newMemoryStore()
+
+	_, err := store.Vehicle().Create(
+		context.Background(),
+		vehiclestore.Vehicle{
+			ShortCode:    "abcd",
+			BatteryLevel: 42,
+			Position:     vehiclestore.Point{Latitude: 12.5, Longitude: 3.2},
+		},
+	)
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		desc       string
+		code       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "existing vehicle",
+			code:       "abcd",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"vehicle":{"id":1,"shortcode":"abcd","battery":42,"latitude":12.5,"longitude":3.2,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}}`,
+		},
+		{
+			desc:       "unknown vehicle",
+			code:       "efgh",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":1004,"message":"The vehicle does not exist","details":{"shortcode":"efgh"}}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			handler := vehicle.NewGetByShortCodeHandler(store, zap.NewNop())
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/vehicles/by-shortcode/"+testCase.code, http.NoBody)
+			req.SetPathValue("code", testCase.code)
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
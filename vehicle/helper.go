@@ -1,6 +1,12 @@
 package vehicle
 
-import "github.com/Cirederf1/vehicle-server/pkg/httputil"
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+)
 
 func newValidationError(issues []string) error {
 	return &httputil.APIError{
@@ -9,3 +15,101 @@ func newValidationError(issues []string) error {
 		Details: issues,
 	}
 }
+
+func newNotFoundError(id int64) error {
+	return &httputil.APIError{
+		Code:    httputil.ErrCodeResourceNotFound,
+		Message: "The vehicle does not exist",
+		Details: map[string]int64{"id": id},
+	}
+}
+
+func newShortCodeNotFoundError(shortCode string) error {
+	return &httputil.APIError{
+		Code:    httputil.ErrCodeResourceNotFound,
+		Message: "The vehicle does not exist",
+		Details: map[string]string{"shortcode": shortCode},
+	}
+}
+
+func newShortCodeConflictError(shortCode string) error {
+	return &httputil.APIError{
+		Code:    httputil.ErrCodeResourceConflict,
+		Message: "The shortcode is already used by another vehicle",
+		Details: map[string]string{"shortcode": shortCode},
+	}
+}
+
+func newShortCodeExhaustedError(attempts int) error {
+	return &httputil.APIError{
+		Code:    httputil.ErrCodeResourceExhausted,
+		Message: "Could not allocate a shortcode, retry or provide one",
+		Details: map[string]int{"attempts": attempts},
+	}
+}
+
+func newTransitionError(err *vehiclestore.TransitionError) error {
+	return &httputil.APIError{
+		Code:    httputil.ErrCodeInvalidStatusTransition,
+		Message: "The vehicle status cannot change to the requested one",
+		Details: map[string]vehiclestore.Status{"from": err.From, "to": err.To},
+	}
+}
+
+func newVehicleUnavailableError(id int64, status vehiclestore.Status) error {
+	return &httputil.APIError{
+		Code:    httputil.ErrCodeResourceConflict,
+		Message: "The vehicle is not available",
+		Details: map[string]any{"id": id, "status": status},
+	}
+}
+
+func newReservationNotFoundError(id int64) error {
+	return &httputil.APIError{
+		Code:    httputil.ErrCodeResourceNotFound,
+		Message: "The reservation does not exist",
+		Details: map[string]int64{"id": id},
+	}
+}
+
+func newReservationEndedError(id int64) error {
+	return &httputil.APIError{
+		Code:    httputil.ErrCodeResourceConflict,
+		Message: "The reservation already ended",
+		Details: map[string]int64{"id": id},
+	}
+}
+
+func newReservationMismatchError(id int64) error {
+	return &httputil.APIError{
+		Code:    httputil.ErrCodeResourceConflict,
+		Message: "The reservation is for another vehicle or rider",
+		Details: map[string]int64{"id": id},
+	}
+}
+
+func newTripNotFoundError(id int64) error {
+	return &httputil.APIError{
+		Code:    httputil.ErrCodeResourceNotFound,
+		Message: "The trip does not exist",
+		Details: map[string]int64{"id": id},
+	}
+}
+
+func newTripEndedError(id int64) error {
+	return &httputil.APIError{
+		Code:    httputil.ErrCodeResourceConflict,
+		Message: "The trip already ended",
+		Details: map[string]int64{"id": id},
+	}
+}
+
+// parseIDFromPath reads the ID of the vehicle, reservation or trip from the {id} path wildcard.
+func parseIDFromPath(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return 0, newValidationError([]string{"id must be an integer"})
+	}
+
+	return id, nil
+}
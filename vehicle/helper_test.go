@@ -0,0 +1,16 @@
+//go:build !integration
+
+package vehicle_test
+
+import (
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/storage"
+)
+
+// now is the time of every change made to the stores returned by newMemoryStore.
+var now = time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC)
+
+func newMemoryStore() *storage.MemoryStore {
+	return storage.NewMemoryStoreWithClock(func() time.Time { return now })
+}
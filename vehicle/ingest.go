@@ -0,0 +1,236 @@
+package vehicle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/telemetrystore"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"go.uber.org/zap"
+)
+
+// maxIngestedLines bounds the size of a batch, which is ingested in a single transaction.
+const maxIngestedLines = 10_000
+
+// errTooManyIngestedLines stops reading the batches having more than maxIngestedLines.
+var errTooManyIngestedLines = errors.New("too many ingested lines")
+
+// TelemetryReading is a line of an ingested batch, reported by a vehicle.
+type TelemetryReading struct {
+	VehicleID    int64     `json:"vehicle_id"`
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	BatteryLevel int64     `json:"battery"`
+	RecordedAt   time.Time `json:"recorded_at"`
+}
+
+func (t *TelemetryReading) validate() []string {
+	var validationIssues []string
+
+	if t.VehicleID == 0 {
+		validationIssues = append(validationIssues, "missing vehicle_id")
+	}
+
+	if t.RecordedAt.IsZero() {
+		validationIssues = append(validationIssues, "missing recorded_at")
+	}
+
+	if t.Latitude < -90 || t.Latitude > 90 {
+		validationIssues = append(validationIssues, "latitude must be >= -90 and <= 90")
+	}
+
+	if t.Longitude < -180 || t.Longitude > 180 {
+		validationIssues = append(validationIssues, "longitude must be >= -180 and <= 180")
+	}
+
+	if t.BatteryLevel < 0 || t.BatteryLevel > 100 {
+		validationIssues = append(validationIssues, "battery level must be >= 0 and <= 100")
+	}
+
+	return validationIssues
+}
+
+func (t *TelemetryReading) record() telemetrystore.Record {
+	return telemetrystore.Record{
+		VehicleID:    t.VehicleID,
+		Position:     vehiclestore.Point{Latitude: t.Latitude, Longitude: t.Longitude},
+		BatteryLevel: t.BatteryLevel,
+		RecordedAt:   t.RecordedAt,
+	}
+}
+
+// IngestError is the error of a line of a batch, the other lines are still ingested.
+type IngestError struct {
+	// Line is the number of the line in the batch, starting at 1.
+	Line  int                `json:"line"`
+	Error *httputil.APIError `json:"error"`
+}
+
+func newIngestError(line int, err error) IngestError {
+	var apiError *httputil.APIError
+	errors.As(err, &apiError)
+
+	return IngestError{Line: line, Error: apiError}
+}
+
+type IngestResponse struct {
+	// Accepted is the number of readings recorded.
+	Accepted int           `json:"accepted"`
+	Errors   []IngestError `json:"errors"`
+}
+
+// IngestHandler records batches of NDJSON telemetry readings: each vehicle moves to
+// its latest reading of the batch, and every reading is added to the history.
+type IngestHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewIngestHandler(store storage.Store, logger *zap.Logger) *IngestHandler {
+	return &IngestHandler{
+		store:  store,
+		logger: logger.With(zap.String("handler", "ingest_telemetry")),
+	}
+}
+
+func (h *IngestHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var (
+		readings   []TelemetryReading
+		lines      []int
+		ingestErrs = []IngestError{}
+		lineCount  int
+	)
+
+	err := httputil.DecodeRequestAsNDJSON(r, func(line int, raw []byte, err error) error {
+		// The batches past the limit are rejected without reading the rest of them.
+		if lineCount++; lineCount > maxIngestedLines {
+			return errTooManyIngestedLines
+		}
+
+		if errors.Is(err, httputil.ErrNDJSONLineTooLong) {
+			ingestErrs = append(ingestErrs, newIngestError(line, newValidationError([]string{
+				fmt.Sprintf("line must have at most %d bytes", httputil.MaxNDJSONLineSize),
+			})))
+			return nil
+		}
+
+		var reading TelemetryReading
+
+		if err := json.Unmarshal(raw, &reading); err != nil {
+			ingestErrs = append(ingestErrs, newIngestError(line, newValidationError([]string{"invalid telemetry reading"})))
+			return nil
+		}
+
+		if validationIssues := reading.validate(); len(validationIssues) > 0 {
+			ingestErrs = append(ingestErrs, newIngestError(line, newValidationError(validationIssues)))
+			return nil
+		}
+
+		readings = append(readings, reading)
+		lines = append(lines, line)
+
+		return nil
+	})
+	if errors.Is(err, errTooManyIngestedLines) {
+		httputil.ServeError(
+			rw,
+			http.StatusBadRequest,
+			newValidationError([]string{fmt.Sprintf("batch must have at most %d lines", maxIngestedLines)}),
+		)
+		return
+	}
+	if err != nil {
+		h.logger.Error(
+			"Could not decode request body",
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	var accepted int
+
+	err = h.store.WithTx(r.Context(), func(tx storage.Store) error {
+		missing, err := tx.Vehicle().ReportPositions(r.Context(), latestPositionReports(readings))
+		if err != nil {
+			return err
+		}
+
+		missingIDs := make(map[int64]struct{}, len(missing))
+		for _, id := range missing {
+			missingIDs[id] = struct{}{}
+		}
+
+		records := make([]telemetrystore.Record, 0, len(readings))
+
+		for i, reading := range readings {
+			if _, ok := missingIDs[reading.VehicleID]; ok {
+				ingestErrs = append(ingestErrs, newIngestError(lines[i], newNotFoundError(reading.VehicleID)))
+				continue
+			}
+
+			records = append(records, reading.record())
+		}
+
+		accepted = len(records)
+
+		return tx.Telemetry().AppendBatch(r.Context(), records)
+	})
+	if err != nil {
+		h.logger.Error(
+			"Could not ingest the telemetry",
+			zap.Int("readings", len(readings)),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	slices.SortFunc(ingestErrs, func(a, b IngestError) int {
+		return a.Line - b.Line
+	})
+
+	httputil.ServeJSON(rw, http.StatusOK, &IngestResponse{Accepted: accepted, Errors: ingestErrs})
+}
+
+// latestPositionReports returns the latest reading of each vehicle, ties going to the last line.
+// The reports are sorted by vehicle ID, so that the concurrent batches lock the vehicles
+// in the same order and do not deadlock.
+func latestPositionReports(readings []TelemetryReading) []vehiclestore.PositionReport {
+	var (
+		latest = make(map[int64]int, len(readings))
+		ids    []int64
+	)
+
+	for i, reading := range readings {
+		j, ok := latest[reading.VehicleID]
+		if !ok {
+			ids = append(ids, reading.VehicleID)
+		}
+
+		if !ok || !reading.RecordedAt.Before(readings[j].RecordedAt) {
+			latest[reading.VehicleID] = i
+		}
+	}
+
+	slices.Sort(ids)
+
+	reports := make([]vehiclestore.PositionReport, 0, len(ids))
+	for _, id := range ids {
+		reading := readings[latest[id]]
+		reports = append(reports, vehiclestore.PositionReport{
+			ID:           id,
+			Position:     vehiclestore.Point{Latitude: reading.Latitude, Longitude: reading.Longitude},
+			BatteryLevel: reading.BatteryLevel,
+			RecordedAt:   reading.RecordedAt,
+		})
+	}
+
+	return reports
+}
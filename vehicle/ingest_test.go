@@ -0,0 +1,175 @@
+//go:build !integration
+
+package vehicle_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/vehicle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestIngestHandler(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = newMemoryStore()
+	)
+
+	for _, shortCode := range []string{"abcd", "efgh"} {
+		_, err := store.Vehicle().Create(
+			ctx,
+			vehiclestore.Vehicle{ShortCode: shortCode, BatteryLevel: 90, Position: vehiclestore.Point{Latitude: 12.5, Longitude: 3.2}},
+		)
+		require.NoError(t, err)
+	}
+
+	body := strings.Join([]string{
+		`{"vehicle_id":1,"latitude":12.6,"longitude":3.3,"battery":80,"recorded_at":"2024-03-01T14:00:00Z"}`,
+		`{"vehicle_id":1,"latitude":12.7,"longitude":3.4,"battery":70,"recorded_at":"2024-03-01T14:01:00Z"}`,
+		``,
+		`{"vehicle_id":2,"latitude":12.8,"longitude":3.5,"battery":150,"recorded_at":"2024-03-01T14:00:00Z"}`,
+		`{"vehicle_id":3,"latitude":12.8,"longitude":3.5,"battery":50,"recorded_at":"2024-03-01T14:00:00Z"}`,
+		`not json`,
+		// Late readings are added to the history, without moving the vehicle back.
+		`{"vehicle_id":1,"latitude":12.5,"longitude":3.2,"battery":85,"recorded_at":"2024-03-01T13:59:00Z"}`,
+		`{"latitude":12.8,"longitude":3.5,"battery":50}`,
+	}, "\n")
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/telemetry", strings.NewReader(body))
+	req.Header.Add("Content-Type", "application/x-ndjson")
+
+	vehicle.NewIngestHandler(store, zap.NewNop()).ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Result().StatusCode)
+	assert.JSONEq(t, `{"accepted":3,"errors":[
+		{"line":4,"error":{"code":1003,"message":"The request payload is invalid","details":["battery level must be >= 0 and <= 100"]}},
+		{"line":5,"error":{"code":1004,"message":"The vehicle does not exist","details":{"id":3}}},
+		{"line":6,"error":{"code":1003,"message":"The request payload is invalid","details":["invalid telemetry reading"]}},
+		{"line":8,"error":{"code":1003,"message":"The request payload is invalid","details":["missing vehicle_id","missing recorded_at"]}}
+	]}`, resp.Body.String())
+
+	got, err := store.Vehicle().Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, vehiclestore.Point{Latitude: 12.7, Longitude: 3.4}, got.Position)
+	assert.Equal(t, int64(70), got.BatteryLevel)
+
+	got, err = store.Vehicle().Get(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(90), got.BatteryLevel)
+
+	history, err := store.Telemetry().History(ctx, 1, now.Add(-24*time.Hour), now.Add(24*time.Hour), 0)
+	require.NoError(t, err)
+	assert.Len(t, history, 3)
+}
+
+func TestIngestHandlerOutOfOrderBatches(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		store   = newMemoryStore()
+		handler = vehicle.NewIngestHandler(store, zap.NewNop())
+	)
+
+	_, err := store.Vehicle().Create(
+		ctx,
+		vehiclestore.Vehicle{ShortCode: "abcd", BatteryLevel: 90, Position: vehiclestore.Point{Latitude: 12.5, Longitude: 3.2}},
+	)
+	require.NoError(t, err)
+
+	// The second batch is delayed, its reading is older than the one of the first batch.
+	for _, body := range []string{
+		`{"vehicle_id":1,"latitude":12.7,"longitude":3.4,"battery":70,"recorded_at":"2024-03-01T14:01:00Z"}`,
+		`{"vehicle_id":1,"latitude":12.6,"longitude":3.3,"battery":80,"recorded_at":"2024-03-01T14:00:00Z"}`,
+	} {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/telemetry", strings.NewReader(body))
+		req.Header.Add("Content-Type", "application/x-ndjson")
+
+		handler.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Result().StatusCode)
+		assert.JSONEq(t, `{"accepted":1,"errors":[]}`, resp.Body.String())
+	}
+
+	got, err := store.Vehicle().Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, vehiclestore.Point{Latitude: 12.7, Longitude: 3.4}, got.Position)
+	assert.Equal(t, int64(70), got.BatteryLevel)
+
+	history, err := store.Telemetry().History(ctx, 1, now.Add(-24*time.Hour), now.Add(24*time.Hour), 0)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+func TestIngestHandlerLongLines(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = newMemoryStore()
+	)
+
+	_, err := store.Vehicle().Create(
+		ctx,
+		vehiclestore.Vehicle{ShortCode: "abcd", BatteryLevel: 90, Position: vehiclestore.Point{Latitude: 12.5, Longitude: 3.2}},
+	)
+	require.NoError(t, err)
+
+	// The first line is longer than the default bufio.Scanner buffer, the second one is over the limit.
+	body := strings.Join([]string{
+		`{"vehicle_id":1,"latitude":12.6,"longitude":3.3,"battery":80,"recorded_at":"2024-03-01T14:00:00Z","firmware":"` + strings.Repeat("a", 100_000) + `"}`,
+		`{"vehicle_id":1,"latitude":12.6,"longitude":3.3,"battery":80,"recorded_at":"2024-03-01T14:00:00Z","firmware":"` + strings.Repeat("a", 2<<20) + `"}`,
+		`{"vehicle_id":1,"latitude":12.7,"longitude":3.4,"battery":70,"recorded_at":"2024-03-01T14:01:00Z"}`,
+	}, "\n")
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/telemetry", strings.NewReader(body))
+	req.Header.Add("Content-Type", "application/x-ndjson")
+
+	vehicle.NewIngestHandler(store, zap.NewNop()).ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Result().StatusCode)
+	assert.JSONEq(t, `{"accepted":2,"errors":[
+		{"line":2,"error":{"code":1003,"message":"The request payload is invalid","details":["line must have at most 1048576 bytes"]}}
+	]}`, resp.Body.String())
+
+	got, err := store.Vehicle().Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, vehiclestore.Point{Latitude: 12.7, Longitude: 3.4}, got.Position)
+}
+
+func TestIngestHandlerContentType(t *testing.T) {
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/telemetry", strings.NewReader(`{}`))
+	req.Header.Add("Content-Type", "application/json")
+
+	vehicle.NewIngestHandler(newMemoryStore(), zap.NewNop()).ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Result().StatusCode)
+	assert.JSONEq(
+		t,
+		`{"code":3,"message":"Unexpected request content type","details":{"expected":"application/x-ndjson","got":"application/json"}}`,
+		resp.Body.String(),
+	)
+}
+
+func TestIngestHandlerTooManyLines(t *testing.T) {
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/telemetry", strings.NewReader(strings.Repeat("{}\n", 10_001)))
+	req.Header.Add("Content-Type", "application/x-ndjson")
+
+	vehicle.NewIngestHandler(newMemoryStore(), zap.NewNop()).ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Result().StatusCode)
+	assert.JSONEq(
+		t,
+		`{"code":1003,"message":"The request payload is invalid","details":["batch must have at most 10000 lines"]}`,
+		resp.Body.String(),
+	)
+}
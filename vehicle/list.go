@@ -1,65 +1,528 @@
 package vehicle
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Cirederf1/vehicle-server/pkg/cursor"
 	"github.com/Cirederf1/vehicle-server/pkg/httputil"
 	"github.com/Cirederf1/vehicle-server/storage"
 	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	geom "github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
 	"go.uber.org/zap"
 )
 
+// defaultListLimit is the number of vehicles listed when the request does not set a limit,
+// unless the maximum limit of the handler is lower.
+const defaultListLimit = 10
+
 type ListRequest struct {
 	Latitude  float64
 	Longitude float64
 	Limit     int64
+	// Radius in meters, 0 when the search is not bounded.
+	Radius float64
+	// BoundingBox restricts the search to an area, instead of the closest vehicles.
+	BoundingBox *vehiclestore.BoundingBox
+	// Polygon restricts the search to an area, instead of the closest vehicles.
+	Polygon vehiclestore.Polygon
+	// Cursor is the NextCursor of the previous page, empty for the first page.
+	Cursor string
+	// MinBattery and MaxBattery bound the battery level of the vehicles, when not nil.
+	MinBattery *int64
+	MaxBattery *int64
+	// Statuses restricts the vehicles to the ones having one of them, when not nil.
+	// The nearest-first searches default to the available vehicles.
+	Statuses []vehiclestore.Status
+	// UpdatedSince restricts the vehicles to the ones updated at or after it, when not nil.
+	UpdatedSince *time.Time
+	// Types restricts the vehicles to the ones having one of them, when not nil.
+	Types []vehiclestore.Type
+}
+
+func (req *ListRequest) options() vehiclestore.ListOptions {
+	return vehiclestore.ListOptions{
+		Limit: req.Limit,
+		Filter: vehiclestore.Filter{
+			MinBattery:   req.MinBattery,
+			MaxBattery:   req.MaxBattery,
+			Statuses:     req.Statuses,
+			UpdatedSince: req.UpdatedSince,
+			Types:        req.Types,
+		},
+	}
+}
+
+// areaDigest identifies the searched area, if any.
+func (req *ListRequest) areaDigest() string {
+	if req.BoundingBox == nil && req.Polygon == nil {
+		return ""
+	}
+
+	return digest([]any{req.BoundingBox, req.Polygon})
+}
+
+// filterDigest identifies the filters of the search, whatever the order of the statuses and types.
+func (req *ListRequest) filterDigest() string {
+	var updatedSince *time.Time
+	if req.UpdatedSince != nil {
+		utc := req.UpdatedSince.UTC()
+		updatedSince = &utc
+	}
+
+	statuses := slices.Clone(req.Statuses)
+	slices.Sort(statuses)
+
+	types := slices.Clone(req.Types)
+	slices.Sort(types)
+
+	return digest([]any{req.MinBattery, req.MaxBattery, statuses, updatedSince, types})
+}
+
+// digest returns a short hash of the JSON encoding of v.
+func digest(v any) string {
+	encoded, _ := json.Marshal(v)
+	sum := sha256.Sum256(encoded)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// newListRequestFromQueryParameters parses the query parameters,
+// it returns the issues of the malformed ones, see validate for the others.
+func newListRequestFromQueryParameters(query url.Values, defaultLimit int64) (*ListRequest, []string) {
+	var (
+		req    = ListRequest{Limit: defaultLimit}
+		parser = queryParser{query: query}
+	)
+
+	req.Latitude, _ = parser.float("latitude", "latitude must be a number")
+	req.Longitude, _ = parser.float("longitude", "longitude must be a number")
+
+	if limit, ok := parser.int("limit", "limit must be an integer"); ok {
+		req.Limit = limit
+	}
+
+	if radius, ok := parser.float("radius", "radius must be a number of meters > 0"); ok {
+		if radius <= 0 {
+			parser.issues = append(parser.issues, "radius must be a number of meters > 0")
+		}
+
+		req.Radius = radius
+	}
+
+	if query.Has("bbox") {
+		box, issues := parseBoundingBox(query.Get("bbox"))
+		parser.issues = append(parser.issues, issues...)
+		req.BoundingBox = box
+	}
+
+	if query.Has("polygon") {
+		polygon, issues := parsePolygon(query.Get("polygon"))
+		parser.issues = append(parser.issues, issues...)
+		req.Polygon = polygon
+	}
+
+	req.Cursor = query.Get("cursor")
+
+	if level, ok := parser.int("min_battery", "min_battery must be an integer >= 0 and <= 100"); ok {
+		req.MinBattery = &level
+	}
+
+	if level, ok := parser.int("max_battery", "max_battery must be an integer >= 0 and <= 100"); ok {
+		req.MaxBattery = &level
+	}
+
+	if since, ok := parser.timestamp("updated_since", "updated_since must be a RFC 3339 timestamp"); ok {
+		req.UpdatedSince = &since
+	}
+
+	nearestFirst := !query.Has("bbox") && !query.Has("polygon")
+
+	switch {
+	case query.Has("status"):
+		for _, status := range strings.Split(query.Get("status"), ",") {
+			req.Statuses = append(req.Statuses, vehiclestore.Status(status))
+		}
+	case nearestFirst:
+		req.Statuses = []vehiclestore.Status{vehiclestore.StatusAvailable}
+	}
+
+	if query.Has("type") {
+		for _, t := range strings.Split(query.Get("type"), ",") {
+			req.Types = append(req.Types, vehiclestore.Type(t))
+		}
+	}
+
+	// The areas do not need a location, the nearest-first searches do.
+	if nearestFirst && (!query.Has("latitude") || !query.Has("longitude")) {
+		parser.issues = append(parser.issues, "missing latitude and longitude")
+	}
+
+	modes := 0
+	for _, mode := range []string{"radius", "bbox", "polygon"} {
+		if query.Has(mode) {
+			modes++
+		}
+	}
+	if modes > 1 {
+		parser.issues = append(parser.issues, "radius, bbox and polygon are mutually exclusive")
+	}
+
+	return &req, parser.issues
+}
+
+func (req *ListRequest) validate(maxLimit int64) []string {
+	var validationIssues []string
+
+	if req.Latitude < -90 || req.Latitude > 90 {
+		validationIssues = append(validationIssues, "latitude must be >= -90 and <= 90")
+	}
+
+	if req.Longitude < -180 || req.Longitude > 180 {
+		validationIssues = append(validationIssues, "longitude must be >= -180 and <= 180")
+	}
+
+	if req.Limit < 1 || req.Limit > maxLimit {
+		validationIssues = append(validationIssues, fmt.Sprintf("limit must be > 0 and <= %d", maxLimit))
+	}
+
+	if req.MinBattery != nil && (*req.MinBattery < 0 || *req.MinBattery > 100) {
+		validationIssues = append(validationIssues, "min_battery must be an integer >= 0 and <= 100")
+	}
+
+	if req.MaxBattery != nil && (*req.MaxBattery < 0 || *req.MaxBattery > 100) {
+		validationIssues = append(validationIssues, "max_battery must be an integer >= 0 and <= 100")
+	}
+
+	if req.MinBattery != nil && req.MaxBattery != nil && *req.MinBattery > *req.MaxBattery {
+		validationIssues = append(validationIssues, "min_battery must be <= max_battery")
+	}
+
+	for _, status := range req.Statuses {
+		if !status.Valid() {
+			validationIssues = append(validationIssues, "status must be a comma separated list of "+joinStatuses(vehiclestore.Statuses))
+			break
+		}
+	}
+
+	for _, t := range req.Types {
+		if !t.Valid() {
+			validationIssues = append(validationIssues, "type must be a comma separated list of "+joinTypes(vehiclestore.Types))
+			break
+		}
+	}
+
+	return validationIssues
+}
+
+// queryParser parses optional query parameters, collecting the issues of the malformed ones.
+type queryParser struct {
+	query  url.Values
+	issues []string
+}
+
+// float parses a number, it returns false when the parameter is missing or malformed.
+func (p *queryParser) float(name, issue string) (float64, bool) {
+	if !p.query.Has(name) {
+		return 0, false
+	}
+
+	v, err := parseFinite(p.query.Get(name))
+	if err != nil {
+		p.issues = append(p.issues, issue)
+		return 0, false
+	}
+
+	return v, true
+}
+
+// errNotFinite is returned when parsing NaN or an infinity, which would get through the range checks.
+var errNotFinite = errors.New("number must be finite")
+
+// parseFinite parses a finite number.
+func parseFinite(raw string) (float64, error) {
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errNotFinite
+	}
+
+	return v, nil
+}
+
+// int parses an integer, it returns false when the parameter is missing or malformed.
+func (p *queryParser) int(name, issue string) (int64, bool) {
+	if !p.query.Has(name) {
+		return 0, false
+	}
+
+	v, err := strconv.ParseInt(p.query.Get(name), 10, 64)
+	if err != nil {
+		p.issues = append(p.issues, issue)
+		return 0, false
+	}
+
+	return v, true
+}
+
+// timestamp parses a RFC 3339 timestamp, it returns false when the parameter is missing or malformed.
+func (p *queryParser) timestamp(name, issue string) (time.Time, bool) {
+	if !p.query.Has(name) {
+		return time.Time{}, false
+	}
+
+	v, err := time.Parse(time.RFC3339, p.query.Get(name))
+	if err != nil {
+		p.issues = append(p.issues, issue)
+		return time.Time{}, false
+	}
+
+	return v, true
+}
+
+// parseBoundingBox parses a GeoJSON like bounding box: min longitude, min latitude,
+// max longitude and max latitude, separated by commas.
+func parseBoundingBox(raw string) (*vehiclestore.BoundingBox, []string) {
+	const invalidBoundingBox = "bbox must be min longitude, min latitude, max longitude and max latitude separated by commas"
+
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return nil, []string{invalidBoundingBox}
+	}
+
+	var coords [4]float64
+	for i, part := range parts {
+		coord, err := parseFinite(strings.TrimSpace(part))
+		if err != nil {
+			return nil, []string{invalidBoundingBox}
+		}
+		coords[i] = coord
+	}
+
+	box := &vehiclestore.BoundingBox{
+		Min: vehiclestore.Point{Longitude: coords[0], Latitude: coords[1]},
+		Max: vehiclestore.Point{Longitude: coords[2], Latitude: coords[3]},
+	}
+
+	var validationIssues []string
+
+	for _, p := range []vehiclestore.Point{box.Min, box.Max} {
+		validationIssues = append(validationIssues, validatePoint("bbox", p)...)
+	}
+
+	if box.Min.Latitude > box.Max.Latitude || box.Min.Longitude > box.Max.Longitude {
+		validationIssues = append(validationIssues, "bbox min coordinates must be lower than its max coordinates")
+	}
+
+	return box, validationIssues
+}
+
+// parsePolygon parses a GeoJSON Polygon geometry.
+func parsePolygon(raw string) (vehiclestore.Polygon, []string) {
+	var g geom.T
+
+	if err := geojson.Unmarshal([]byte(raw), &g); err != nil {
+		return nil, []string{"polygon must be a GeoJSON Polygon"}
+	}
+
+	geojsonPolygon, ok := g.(*geom.Polygon)
+	if !ok || geojsonPolygon.NumLinearRings() == 0 {
+		return nil, []string{"polygon must be a GeoJSON Polygon"}
+	}
+
+	var (
+		polygon          = make(vehiclestore.Polygon, geojsonPolygon.NumLinearRings())
+		validationIssues []string
+	)
+
+	for i := range polygon {
+		coords := geojsonPolygon.LinearRing(i).Coords()
+
+		if len(coords) < 4 || !coords[0].Equal(geom.XY, coords[len(coords)-1]) {
+			validationIssues = append(validationIssues, "polygon rings must be closed and have at least 4 positions")
+			continue
+		}
+
+		polygon[i] = make([]vehiclestore.Point, len(coords))
+		for j, c := range coords {
+			polygon[i][j] = vehiclestore.Point{Longitude: c.X(), Latitude: c.Y()}
+			validationIssues = append(validationIssues, validatePoint("polygon", polygon[i][j])...)
+		}
+	}
+
+	// Every position reports its own issues, only keep one of each.
+	slices.Sort(validationIssues)
+
+	return polygon, slices.Compact(validationIssues)
+}
+
+func validatePoint(field string, p vehiclestore.Point) []string {
+	var validationIssues []string
+
+	if p.Latitude < -90 || p.Latitude > 90 {
+		validationIssues = append(validationIssues, field+" latitudes must be >= -90 and <= 90")
+	}
+
+	if p.Longitude < -180 || p.Longitude > 180 {
+		validationIssues = append(validationIssues, field+" longitudes must be >= -180 and <= 180")
+	}
+
+	return validationIssues
+}
+
+// listCursor is the signed payload of the cursors.
+// It records the query it was issued for, so that it is not reused with another one.
+type listCursor struct {
+	Distance  float64 `json:"d"`
+	ID        int64   `json:"id"`
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lon"`
+	Radius    float64 `json:"r,omitempty"`
+	Area      string  `json:"a,omitempty"`
+	Filter    string  `json:"f"`
+}
+
+func newListCursor(req *ListRequest, next *vehiclestore.Cursor) *listCursor {
+	return &listCursor{
+		Distance:  next.Distance,
+		ID:        next.ID,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Radius:    req.Radius,
+		Area:      req.areaDigest(),
+		Filter:    req.filterDigest(),
+	}
+}
+
+func (c *listCursor) matches(req *ListRequest) bool {
+	return c.Latitude == req.Latitude &&
+		c.Longitude == req.Longitude &&
+		c.Radius == req.Radius &&
+		c.Area == req.areaDigest() &&
+		c.Filter == req.filterDigest()
 }
 
-func newListRequestFromQueryParameters(r *http.Request) *ListRequest {
-	var req ListRequest
+// ListedVehicle is a vehicle of a ListResponse.
+type ListedVehicle struct {
+	Vehicle
+	// Distance in meters and Bearing in degrees clockwise from the north, from the searched location.
+	// They are only set for the nearest-first searches, and there is no bearing at the searched location.
+	Distance *float64 `json:"distance,omitempty"`
+	Bearing  *float64 `json:"bearing,omitempty"`
+}
+
+func newListedVehicleFromModel(v vehiclestore.Vehicle) ListedVehicle {
+	listed := ListedVehicle{Vehicle: newVehicleFromModel(v)}
 
-	req.Latitude, _ = strconv.ParseFloat(r.URL.Query().Get("latitude"), 64)
-	req.Longitude, _ = strconv.ParseFloat(r.URL.Query().Get("longitude"), 64)
-	req.Limit, _ = strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+	// Rounded to the precision of the positions sent by the vehicles.
+	if v.Distance != nil {
+		distance := math.Round(*v.Distance)
+		listed.Distance = &distance
+	}
+
+	if v.Bearing != nil {
+		bearing := math.Round(*v.Bearing*10) / 10
+		listed.Bearing = &bearing
+	}
 
-	return &req
+	return listed
 }
 
 type ListResponse struct {
-	Vehicles []Vehicle `json:"vehicles"`
+	Vehicles []ListedVehicle `json:"vehicles"`
+	// NextCursor is set when there might be more vehicles,
+	// pass it as the cursor query parameter to get them.
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
-func newListResponse(vehicles []vehiclestore.Vehicle) *ListResponse {
-	result := make([]Vehicle, len(vehicles))
+func newListResponse(vehicles []vehiclestore.Vehicle, nextCursor string) *ListResponse {
+	result := make([]ListedVehicle, len(vehicles))
 
 	for i, v := range vehicles {
-		result[i] = newVehicleFromModel(v)
+		result[i] = newListedVehicleFromModel(v)
 	}
 
-	return &ListResponse{Vehicles: result}
+	return &ListResponse{Vehicles: result, NextCursor: nextCursor}
 }
 
 type ListHandler struct {
-	store  storage.Store
-	logger *zap.Logger
+	store    storage.Store
+	cursors  *cursor.Codec
+	maxLimit int64
+	logger   *zap.Logger
 }
 
-func NewListHandler(store storage.Store, logger *zap.Logger) *ListHandler {
+// NewListHandler returns a handler listing at most maxLimit vehicles per page.
+func NewListHandler(store storage.Store, cursors *cursor.Codec, maxLimit int64, logger *zap.Logger) *ListHandler {
 	return &ListHandler{
-		store:  store,
-		logger: logger.With(zap.String("handler", "list_vehicles")),
+		store:    store,
+		cursors:  cursors,
+		maxLimit: maxLimit,
+		logger:   logger.With(zap.String("handler", "list_vehicles")),
 	}
 }
 
 func (l *ListHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
-	req := newListRequestFromQueryParameters(r)
+	req, validationIssues := newListRequestFromQueryParameters(r.URL.Query(), min(defaultListLimit, l.maxLimit))
+	validationIssues = append(validationIssues, req.validate(l.maxLimit)...)
+	if len(validationIssues) > 0 {
+		httputil.ServeError(
+			rw,
+			http.StatusBadRequest,
+			newValidationError(validationIssues),
+		)
+		return
+	}
+
+	opts := req.options()
+
+	if req.Cursor != "" {
+		var c listCursor
 
-	vehicles, err := l.store.Vehicle().FindClosestFrom(
-		r.Context(),
-		vehiclestore.Point{Latitude: req.Latitude, Longitude: req.Longitude},
-		req.Limit,
+		if err := l.cursors.Decode(req.Cursor, &c); err != nil {
+			httputil.ServeError(rw, http.StatusBadRequest, newValidationError([]string{"invalid cursor"}))
+			return
+		}
+
+		if !c.matches(req) {
+			httputil.ServeError(rw, http.StatusBadRequest, newValidationError([]string{"cursor does not match the query"}))
+			return
+		}
+
+		opts.After = &vehiclestore.Cursor{Distance: c.Distance, ID: c.ID}
+	}
+
+	var (
+		location = vehiclestore.Point{Latitude: req.Latitude, Longitude: req.Longitude}
+		vehicles []vehiclestore.Vehicle
+		next     *vehiclestore.Cursor
+		err      error
 	)
+
+	switch {
+	case req.BoundingBox != nil:
+		vehicles, next, err = l.store.Vehicle().FindInBoundingBox(r.Context(), *req.BoundingBox, opts)
+	case req.Polygon != nil:
+		vehicles, next, err = l.store.Vehicle().FindInPolygon(r.Context(), req.Polygon, opts)
+	case req.Radius > 0:
+		vehicles, next, err = l.store.Vehicle().FindWithinRadius(r.Context(), location, req.Radius, opts)
+	default:
+		vehicles, next, err = l.store.Vehicle().FindClosestFrom(r.Context(), location, opts)
+	}
 	if err != nil {
 		l.logger.Error(
 			"Could not list vehicles from store",
@@ -70,5 +533,20 @@ func (l *ListHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	httputil.ServeJSON(rw, http.StatusOK, newListResponse(vehicles))
+	var nextCursor string
+
+	if next != nil {
+		nextCursor, err = l.cursors.Encode(newListCursor(req, next))
+		if err != nil {
+			l.logger.Error(
+				"Could not encode the next cursor",
+				zap.Error(err),
+			)
+
+			httputil.ServeError(rw, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, newListResponse(vehicles, nextCursor))
 }
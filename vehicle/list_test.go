@@ -0,0 +1,473 @@
+//go:build !integration
+
+package vehicle_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/cursor"
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/vehicle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestListHandler(t *testing.T) {
+	store := newMemoryStore()
+
+	for _, v := range []vehiclestore.Vehicle{
+		{Position: vehiclestore.Point{Latitude: 50.0, Longitude: 50.0}, ShortCode: "aaa", BatteryLevel: 40},
+		{Position: vehiclestore.Point{Latitude: 51.0, Longitude: 51.0}, ShortCode: "bbb", BatteryLevel: 50},
+		{Position: vehiclestore.Point{Latitude: 52.0, Longitude: 52.0}, ShortCode: "ccc", BatteryLevel: 60},
+	} {
+		_, err := store.Vehicle().Create(context.Background(), v)
+		require.NoError(t, err)
+	}
+
+	for _, testCase := range []struct {
+		desc       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "closest vehicles",
+			query:      "latitude=49&longitude=49&limit=3",
+			wantStatus: http.StatusOK,
+			wantBody: `{"vehicles":[
+				{"id":1,"shortcode":"aaa","battery":40,"latitude":50,"longitude":50,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z","distance":132585,"bearing":32.6},
+				{"id":2,"shortcode":"bbb","battery":50,"latitude":51,"longitude":51,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z","distance":264348,"bearing":32},
+				{"id":3,"shortcode":"ccc","battery":60,"latitude":52,"longitude":52,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z","distance":395272,"bearing":31.3}
+			]}`,
+		},
+		{
+			desc:       "vehicles within a radius",
+			query:      "latitude=50&longitude=50&radius=150000",
+			wantStatus: http.StatusOK,
+			wantBody: `{"vehicles":[
+				{"id":1,"shortcode":"aaa","battery":40,"latitude":50,"longitude":50,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z","distance":0},
+				{"id":2,"shortcode":"bbb","battery":50,"latitude":51,"longitude":51,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z","distance":131781,"bearing":32.1}
+			]}`,
+		},
+		{
+			desc:       "vehicles within a radius, limited",
+			query:      "latitude=50&longitude=50&radius=150000&limit=2",
+			wantStatus: http.StatusOK,
+			wantBody: `{"vehicles":[
+				{"id":1,"shortcode":"aaa","battery":40,"latitude":50,"longitude":50,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z","distance":0},
+				{"id":2,"shortcode":"bbb","battery":50,"latitude":51,"longitude":51,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z","distance":131781,"bearing":32.1}
+			]}`,
+		},
+		{
+			desc:       "vehicles inside a bounding box",
+			query:      "bbox=50.5,50.5,52.5,52.5",
+			wantStatus: http.StatusOK,
+			wantBody: `{"vehicles":[
+				{"id":2,"shortcode":"bbb","battery":50,"latitude":51,"longitude":51,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"},
+				{"id":3,"shortcode":"ccc","battery":60,"latitude":52,"longitude":52,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}
+			]}`,
+		},
+		{
+			desc:       "vehicles inside a polygon",
+			query:      "polygon=" + url.QueryEscape(`{"type":"Polygon","coordinates":[[[49,49],[51.5,49],[49,51.5],[49,49]]]}`),
+			wantStatus: http.StatusOK,
+			wantBody: `{"vehicles":[
+				{"id":1,"shortcode":"aaa","battery":40,"latitude":50,"longitude":50,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}
+			]}`,
+		},
+		{
+			desc:       "invalid bounding box",
+			query:      "bbox=52,50,50,200",
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"code":1003,"message":"The request payload is invalid","details":[
+				"bbox latitudes must be >= -90 and <= 90",
+				"bbox min coordinates must be lower than its max coordinates"
+			]}`,
+		},
+		{
+			desc:       "invalid polygon",
+			query:      "polygon=" + url.QueryEscape(`{"type":"Point","coordinates":[49,49]}`),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["polygon must be a GeoJSON Polygon"]}`,
+		},
+		{
+			desc:       "several search modes",
+			query:      "latitude=50&longitude=50&radius=100&bbox=50,50,52,52",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["radius, bbox and polygon are mutually exclusive"]}`,
+		},
+		{
+			desc:       "vehicles filtered by battery",
+			query:      "latitude=49&longitude=49&limit=3&min_battery=45&max_battery=55",
+			wantStatus: http.StatusOK,
+			wantBody: `{"vehicles":[
+				{"id":2,"shortcode":"bbb","battery":50,"latitude":51,"longitude":51,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z","distance":264348,"bearing":32}
+			]}`,
+		},
+		{
+			desc:       "malformed battery filters",
+			query:      "latitude=49&longitude=49&min_battery=full&max_battery=120",
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"code":1003,"message":"The request payload is invalid","details":[
+				"min_battery must be an integer >= 0 and <= 100",
+				"max_battery must be an integer >= 0 and <= 100"
+			]}`,
+		},
+		{
+			desc:       "inverted battery filters",
+			query:      "latitude=49&longitude=49&min_battery=60&max_battery=40",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["min_battery must be <= max_battery"]}`,
+		},
+		{
+			desc:       "invalid radius",
+			query:      "latitude=50&longitude=50&radius=-3",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["radius must be a number of meters > 0"]}`,
+		},
+		{
+			desc:       "missing location",
+			query:      "latitude=50&radius=100",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["missing latitude and longitude"]}`,
+		},
+		{
+			desc:       "malformed location and limit",
+			query:      "latitude=north&longitude=50&limit=ten",
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"code":1003,"message":"The request payload is invalid","details":[
+				"latitude must be a number",
+				"limit must be an integer"
+			]}`,
+		},
+		{
+			desc:       "non-finite location and radius",
+			query:      "latitude=NaN&longitude=-Inf&radius=Inf",
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"code":1003,"message":"The request payload is invalid","details":[
+				"latitude must be a number",
+				"longitude must be a number",
+				"radius must be a number of meters > 0"
+			]}`,
+		},
+		{
+			desc:       "non-finite bounding box",
+			query:      "bbox=50,50,NaN,52",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["bbox must be min longitude, min latitude, max longitude and max latitude separated by commas"]}`,
+		},
+		{
+			desc:       "out of range location and limit",
+			query:      "latitude=91&longitude=-181&limit=101",
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"code":1003,"message":"The request payload is invalid","details":[
+				"latitude must be >= -90 and <= 90",
+				"longitude must be >= -180 and <= 180",
+				"limit must be > 0 and <= 100"
+			]}`,
+		},
+		{
+			desc:       "zero limit",
+			query:      "latitude=50&longitude=50&limit=0",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["limit must be > 0 and <= 100"]}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			handler := vehicle.NewListHandler(store, cursor.NewCodec([]byte("secret")), 100, zap.NewNop())
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/vehicles?"+testCase.query, http.NoBody)
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestListHandlerPagination(t *testing.T) {
+	var (
+		store   = newMemoryStore()
+		handler = vehicle.NewListHandler(store, cursor.NewCodec([]byte("secret")), 100, zap.NewNop())
+	)
+
+	for _, shortCode := range []string{"aaa", "bbb", "ccc"} {
+		_, err := store.Vehicle().Create(
+			context.Background(),
+			vehiclestore.Vehicle{ShortCode: shortCode, Position: vehiclestore.Point{Latitude: 50, Longitude: 50}},
+		)
+		require.NoError(t, err)
+	}
+
+	list := func(query string) (int, *vehicle.ListResponse) {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/vehicles?"+query, http.NoBody))
+
+		var payload vehicle.ListResponse
+		require.NoError(t, httputil.DecodeJSON(resp.Result().Body, &payload))
+
+		return resp.Result().StatusCode, &payload
+	}
+
+	status, page := list("latitude=49&longitude=49&limit=2")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, page.Vehicles, 2)
+	require.NotEmpty(t, page.NextCursor)
+	assert.Equal(t, []int64{1, 2}, []int64{page.Vehicles[0].ID, page.Vehicles[1].ID})
+
+	status, page = list("latitude=49&longitude=49&limit=2&cursor=" + page.NextCursor)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, page.Vehicles, 1)
+	assert.Equal(t, int64(3), page.Vehicles[0].ID)
+	assert.Empty(t, page.NextCursor)
+
+	// A cursor cannot be reused for another location.
+	_, page = list("latitude=49&longitude=49&limit=1")
+	status, _ = list("latitude=10&longitude=10&limit=1&cursor=" + page.NextCursor)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Nor for other filters.
+	_, page = list("latitude=49&longitude=49&limit=1&status=available,maintenance")
+	status, _ = list("latitude=49&longitude=49&limit=1&status=available&cursor=" + page.NextCursor)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = list("latitude=49&longitude=49&limit=1&status=available,maintenance&min_battery=10&cursor=" + page.NextCursor)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = list("latitude=49&longitude=49&limit=1&status=maintenance,available&cursor=" + page.NextCursor)
+	assert.Equal(t, http.StatusOK, status)
+
+	// Nor forged.
+	status, _ = list("latitude=49&longitude=49&limit=1&cursor=eyJpZCI6MX0.c2lnbmF0dXJl")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestListHandlerDefaultLimit(t *testing.T) {
+	var (
+		store   = newMemoryStore()
+		handler = vehicle.NewListHandler(store, cursor.NewCodec([]byte("secret")), 2, zap.NewNop())
+	)
+
+	for _, shortCode := range []string{"aaa", "bbb", "ccc"} {
+		_, err := store.Vehicle().Create(
+			context.Background(),
+			vehiclestore.Vehicle{ShortCode: shortCode, Position: vehiclestore.Point{Latitude: 50, Longitude: 50}},
+		)
+		require.NoError(t, err)
+	}
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/vehicles?latitude=49&longitude=49", http.NoBody))
+	require.Equal(t, http.StatusOK, resp.Result().StatusCode)
+
+	var page vehicle.ListResponse
+	require.NoError(t, httputil.DecodeJSON(resp.Result().Body, &page))
+
+	// Without a limit, the page is bounded by the maximum limit of the handler.
+	assert.Len(t, page.Vehicles, 2)
+	assert.NotEmpty(t, page.NextCursor)
+}
+
+func TestListHandlerStatus(t *testing.T) {
+	store := newMemoryStore()
+
+	for _, shortCode := range []string{"aaa", "bbb"} {
+		_, err := store.Vehicle().Create(
+			context.Background(),
+			vehiclestore.Vehicle{ShortCode: shortCode, BatteryLevel: 50, Position: vehiclestore.Point{Latitude: 50, Longitude: 50}},
+		)
+		require.NoError(t, err)
+	}
+
+	_, err := store.Vehicle().Transition(context.Background(), 2, vehiclestore.StatusMaintenance)
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		desc       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "closest vehicles are available by default",
+			query:      "latitude=50&longitude=50",
+			wantStatus: http.StatusOK,
+			wantBody: `{"vehicles":[
+				{"id":1,"shortcode":"aaa","battery":50,"latitude":50,"longitude":50,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z","distance":0}
+			]}`,
+		},
+		{
+			desc:       "closest vehicles with a status",
+			query:      "latitude=50&longitude=50&status=maintenance,retired",
+			wantStatus: http.StatusOK,
+			wantBody: `{"vehicles":[
+				{"id":2,"shortcode":"bbb","battery":50,"latitude":50,"longitude":50,"type":"scooter","status":"maintenance","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z","distance":0}
+			]}`,
+		},
+		{
+			desc:       "vehicles of any status inside an area",
+			query:      "bbox=49,49,51,51",
+			wantStatus: http.StatusOK,
+			wantBody: `{"vehicles":[
+				{"id":1,"shortcode":"aaa","battery":50,"latitude":50,"longitude":50,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"},
+				{"id":2,"shortcode":"bbb","battery":50,"latitude":50,"longitude":50,"type":"scooter","status":"maintenance","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}
+			]}`,
+		},
+		{
+			desc:       "unknown status",
+			query:      "latitude=50&longitude=50&status=available,broken",
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"code":1003,"message":"The request payload is invalid","details":[
+				"status must be a comma separated list of available, reserved, in_use, maintenance, retired"
+			]}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			handler := vehicle.NewListHandler(store, cursor.NewCodec([]byte("secret")), 100, zap.NewNop())
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/vehicles?"+testCase.query, http.NoBody)
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestListHandlerUpdatedSince(t *testing.T) {
+	var (
+		clock = now
+		store = storage.NewMemoryStoreWithClock(func() time.Time { return clock })
+	)
+
+	for _, shortCode := range []string{"aaa", "bbb"} {
+		_, err := store.Vehicle().Create(
+			context.Background(),
+			vehiclestore.Vehicle{ShortCode: shortCode, BatteryLevel: 50, Position: vehiclestore.Point{Latitude: 50, Longitude: 50}},
+		)
+		require.NoError(t, err)
+	}
+
+	clock = now.Add(time.Hour)
+	_, err := store.Vehicle().Transition(context.Background(), 2, vehiclestore.StatusMaintenance)
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		desc       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "vehicles updated since a time",
+			query:      "bbox=49,49,51,51&updated_since=2024-03-01T13:00:00Z",
+			wantStatus: http.StatusOK,
+			wantBody: `{"vehicles":[
+				{"id":2,"shortcode":"bbb","battery":50,"latitude":50,"longitude":50,"type":"scooter","status":"maintenance","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T13:30:00Z"}
+			]}`,
+		},
+		{
+			desc:       "vehicles updated since a time in another time zone",
+			query:      "bbox=49,49,51,51&updated_since=2024-03-01T14:30:00%2B02:00",
+			wantStatus: http.StatusOK,
+			wantBody: `{"vehicles":[
+				{"id":1,"shortcode":"aaa","battery":50,"latitude":50,"longitude":50,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"},
+				{"id":2,"shortcode":"bbb","battery":50,"latitude":50,"longitude":50,"type":"scooter","status":"maintenance","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T13:30:00Z"}
+			]}`,
+		},
+		{
+			desc:       "malformed time",
+			query:      "bbox=49,49,51,51&updated_since=yesterday",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["updated_since must be a RFC 3339 timestamp"]}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			handler := vehicle.NewListHandler(store, cursor.NewCodec([]byte("secret")), 100, zap.NewNop())
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/vehicles?"+testCase.query, http.NoBody)
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestListHandlerType(t *testing.T) {
+	var (
+		store     = newMemoryStore()
+		helmetBox = true
+	)
+
+	for _, v := range []vehiclestore.Vehicle{
+		{ShortCode: "aaa", Type: vehiclestore.TypeScooter},
+		{ShortCode: "bbb", Type: vehiclestore.TypeBike},
+		{ShortCode: "ccc", Type: vehiclestore.TypeMoped, Attributes: vehiclestore.Attributes{HelmetBox: &helmetBox}},
+	} {
+		v.BatteryLevel = 50
+		v.Position = vehiclestore.Point{Latitude: 50, Longitude: 50}
+
+		_, err := store.Vehicle().Create(context.Background(), v)
+		require.NoError(t, err)
+	}
+
+	for _, testCase := range []struct {
+		desc       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "vehicles of a type",
+			query:      "bbox=49,49,51,51&type=moped",
+			wantStatus: http.StatusOK,
+			wantBody: `{"vehicles":[
+				{"id":3,"shortcode":"ccc","battery":50,"latitude":50,"longitude":50,"type":"moped","helmet_box":true,"status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}
+			]}`,
+		},
+		{
+			desc:       "closest vehicles of several types",
+			query:      "latitude=50&longitude=50&type=scooter,bike",
+			wantStatus: http.StatusOK,
+			wantBody: `{"vehicles":[
+				{"id":1,"shortcode":"aaa","battery":50,"latitude":50,"longitude":50,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z","distance":0},
+				{"id":2,"shortcode":"bbb","battery":50,"latitude":50,"longitude":50,"type":"bike","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z","distance":0}
+			]}`,
+		},
+		{
+			desc:       "unknown type",
+			query:      "bbox=49,49,51,51&type=scooter,car",
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"code":1003,"message":"The request payload is invalid","details":[
+				"type must be a comma separated list of scooter, bike, moped"
+			]}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			handler := vehicle.NewListHandler(store, cursor.NewCodec([]byte("secret")), 100, zap.NewNop())
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/vehicles?"+testCase.query, http.NoBody)
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
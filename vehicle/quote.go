@@ -0,0 +1,167 @@
+package vehicle
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/pkg/pricing"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"go.uber.org/zap"
+)
+
+// FareItem is a line of a fare, its amount is in the minor unit of the currency.
+type FareItem struct {
+	Kind   string `json:"kind"`
+	Amount int64  `json:"amount"`
+}
+
+// Fare is the itemized price of a trip, its total is the sum of the items.
+type Fare struct {
+	Currency string     `json:"currency"`
+	Items    []FareItem `json:"items"`
+	Total    int64      `json:"total"`
+}
+
+func newFareFromModel(f pricing.Fare) Fare {
+	fare := Fare{
+		Currency: f.Currency,
+		Items:    make([]FareItem, len(f.Items)),
+		Total:    f.Total,
+	}
+
+	for i, item := range f.Items {
+		fare.Items[i] = FareItem{Kind: string(item.Kind), Amount: item.Amount}
+	}
+
+	return fare
+}
+
+// QuoteRequest estimates the fare of a trip from the current position of a vehicle to a destination.
+type QuoteRequest struct {
+	VehicleID int64
+	Latitude  float64
+	Longitude float64
+}
+
+// newQuoteRequestFromQueryParameters parses the query parameters,
+// it returns the issues of the malformed ones, see validate for the others.
+func newQuoteRequestFromQueryParameters(query url.Values) (*QuoteRequest, []string) {
+	var (
+		req    QuoteRequest
+		parser = queryParser{query: query}
+	)
+
+	req.VehicleID, _ = parser.int("vehicle_id", "vehicle_id must be an integer")
+	req.Latitude, _ = parser.float("latitude", "latitude must be a number")
+	req.Longitude, _ = parser.float("longitude", "longitude must be a number")
+
+	if !query.Has("latitude") || !query.Has("longitude") {
+		parser.issues = append(parser.issues, "missing latitude and longitude")
+	}
+
+	return &req, parser.issues
+}
+
+func (req *QuoteRequest) validate() []string {
+	var validationIssues []string
+
+	if req.VehicleID == 0 {
+		validationIssues = append(validationIssues, "missing vehicle_id")
+	}
+
+	if req.Latitude < -90 || req.Latitude > 90 {
+		validationIssues = append(validationIssues, "latitude must be >= -90 and <= 90")
+	}
+
+	if req.Longitude < -180 || req.Longitude > 180 {
+		validationIssues = append(validationIssues, "longitude must be >= -180 and <= 180")
+	}
+
+	return validationIssues
+}
+
+// Quote is the estimated trip to a destination, starting now.
+type Quote struct {
+	VehicleID int64 `json:"vehicle_id"`
+	// Distance is in meters, as the crow flies.
+	Distance float64 `json:"distance"`
+	// Duration is in seconds, at the average speed of the vehicle type.
+	Duration int64 `json:"duration"`
+	Fare     Fare  `json:"fare"`
+}
+
+type QuoteResponse struct {
+	Quote Quote `json:"quote"`
+}
+
+// QuoteHandler estimates the fares with the tariff of the vehicle type.
+type QuoteHandler struct {
+	store  storage.Store
+	pricer *pricing.Pricer
+	// now tells the start of the quoted trips.
+	now    func() time.Time
+	logger *zap.Logger
+}
+
+func NewQuoteHandler(store storage.Store, pricer *pricing.Pricer, now func() time.Time, logger *zap.Logger) *QuoteHandler {
+	return &QuoteHandler{
+		store:  store,
+		pricer: pricer,
+		now:    now,
+		logger: logger.With(zap.String("handler", "quote")),
+	}
+}
+
+func (h *QuoteHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	req, validationIssues := newQuoteRequestFromQueryParameters(r.URL.Query())
+	validationIssues = append(validationIssues, req.validate()...)
+	if len(validationIssues) > 0 {
+		httputil.ServeError(rw, http.StatusBadRequest, newValidationError(validationIssues))
+		return
+	}
+
+	v, err := h.store.Vehicle().Get(r.Context(), req.VehicleID)
+	switch {
+	case errors.Is(err, vehiclestore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newNotFoundError(req.VehicleID))
+		return
+	case err != nil:
+		h.logger.Error(
+			"Could not get the vehicle",
+			zap.Int64("vehicle-id", req.VehicleID),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	distance := vehiclestore.PathLength([]vehiclestore.Point{
+		v.Position,
+		{Latitude: req.Latitude, Longitude: req.Longitude},
+	})
+
+	estimate, err := h.pricer.Quote(string(v.Type), h.now().UTC(), distance)
+	if err != nil {
+		h.logger.Error(
+			"Could not quote the trip",
+			zap.Int64("vehicle-id", req.VehicleID),
+			zap.String("type", string(v.Type)),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, &QuoteResponse{
+		Quote: Quote{
+			VehicleID: req.VehicleID,
+			Distance:  estimate.Ride.Distance,
+			Duration:  int64(estimate.Ride.Duration / time.Second),
+			Fare:      newFareFromModel(estimate.Fare),
+		},
+	})
+}
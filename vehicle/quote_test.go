@@ -0,0 +1,116 @@
+//go:build !integration
+
+package vehicle_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/pkg/pricing"
+	"github.com/Cirederf1/vehicle-server/vehicle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestQuoteHandler(t *testing.T) {
+	var (
+		clock   = now
+		store   = newReservableStore(t, newMemoryStore())
+		handler = vehicle.NewQuoteHandler(store, newPricer(t), func() time.Time { return clock }, zap.NewNop())
+	)
+
+	// 3 km north of the vehicle, ridden in 12 minutes at 15 km/h.
+	resp := serve(handler, http.MethodGet, "/quotes?vehicle_id=1&latitude=50.02698&longitude=50", "", "")
+	require.Equal(t, http.StatusOK, resp.Result().StatusCode)
+
+	var quoted vehicle.QuoteResponse
+	require.NoError(t, httputil.DecodeJSON(resp.Result().Body, &quoted))
+	assert.InDelta(t, 3000, quoted.Quote.Distance, 1)
+	assert.Equal(t, int64(12*60), quoted.Quote.Duration)
+	assert.Equal(
+		t,
+		vehicle.Fare{
+			Currency: "EUR",
+			Items: []vehicle.FareItem{
+				{Kind: "unlock", Amount: 100},
+				{Kind: "time", Amount: 300},
+				{Kind: "distance", Amount: 30},
+			},
+			Total: 430,
+		},
+		quoted.Quote.Fare,
+	)
+
+	// The same trip costs more at night.
+	clock = now.Add(11 * time.Hour)
+
+	resp = serve(handler, http.MethodGet, "/quotes?vehicle_id=1&latitude=50.02698&longitude=50", "", "")
+	require.Equal(t, http.StatusOK, resp.Result().StatusCode)
+	require.NoError(t, httputil.DecodeJSON(resp.Result().Body, &quoted))
+	assert.Equal(t, pricing.ItemTimeOfDay, pricing.ItemKind(quoted.Quote.Fare.Items[3].Kind))
+	assert.Equal(t, int64(595), quoted.Quote.Fare.Total)
+
+	for _, testCase := range []struct {
+		desc       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "unknown vehicle",
+			query:      "vehicle_id=3&latitude=50&longitude=50",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":1004,"message":"The vehicle does not exist","details":{"id":3}}`,
+		},
+		{
+			desc:       "missing parameters",
+			query:      "",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["missing latitude and longitude","missing vehicle_id"]}`,
+		},
+		{
+			desc:       "invalid parameters",
+			query:      "vehicle_id=one&latitude=north&longitude=190",
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"code":1003,"message":"The request payload is invalid","details":[
+				"vehicle_id must be an integer",
+				"latitude must be a number",
+				"missing vehicle_id",
+				"longitude must be >= -180 and <= 180"
+			]}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			resp := serve(handler, http.MethodGet, "/quotes?"+testCase.query, "", "")
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
+
+// newPricer returns a pricer of the scooters, charging half more from 22:00 to 06:00 UTC.
+func newPricer(t *testing.T) *pricing.Pricer {
+	t.Helper()
+
+	pricer, err := pricing.NewPricer(
+		map[string]pricing.Tariff{
+			"scooter": {
+				Currency:     "EUR",
+				UnlockFee:    100,
+				PerMinute:    25,
+				PerKilometer: 10,
+				MaxFare:      3000,
+				Multipliers:  []pricing.Multiplier{{From: 22 * 60, To: 6 * 60, Factor: 1.5}},
+				AverageSpeed: 15,
+			},
+		},
+		time.UTC,
+	)
+	require.NoError(t, err)
+
+	return pricer
+}
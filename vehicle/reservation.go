@@ -0,0 +1,297 @@
+package vehicle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/reservationstore"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"go.uber.org/zap"
+)
+
+// Reservation holds a vehicle for a rider, its vehicle is reserved until it is canceled or expires.
+type Reservation struct {
+	ID        int64      `json:"id"`
+	VehicleID int64      `json:"vehicle_id"`
+	RiderID   string     `json:"rider_id"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+func newReservationFromModel(r reservationstore.Reservation) Reservation {
+	return Reservation{
+		ID:        r.ID,
+		VehicleID: r.VehicleID,
+		RiderID:   r.RiderID,
+		Status:    string(r.Status),
+		CreatedAt: r.CreatedAt,
+		ExpiresAt: r.ExpiresAt,
+		EndedAt:   r.EndedAt,
+	}
+}
+
+type ReservationResponse struct {
+	Reservation Reservation `json:"reservation"`
+}
+
+type ReserveRequest struct {
+	VehicleID int64  `json:"vehicle_id"`
+	RiderID   string `json:"rider_id"`
+}
+
+func (f *ReserveRequest) validate() []string {
+	var validationIssues []string
+
+	if f.VehicleID == 0 {
+		validationIssues = append(validationIssues, "missing vehicle_id")
+	}
+
+	if f.RiderID == "" {
+		validationIssues = append(validationIssues, "missing rider_id")
+	}
+
+	return validationIssues
+}
+
+// ReserveHandler reserves an available vehicle for a rider, for the hold duration.
+type ReserveHandler struct {
+	store  storage.Store
+	hold   time.Duration
+	logger *zap.Logger
+}
+
+func NewReserveHandler(store storage.Store, hold time.Duration, logger *zap.Logger) *ReserveHandler {
+	return &ReserveHandler{
+		store:  store,
+		hold:   hold,
+		logger: logger.With(zap.String("handler", "create_reservation")),
+	}
+}
+
+func (h *ReserveHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var req ReserveRequest
+
+	if err := httputil.DecodeRequestAsJSON(r, &req); err != nil {
+		h.logger.Error(
+			"Could not decode request body",
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	if validationIssues := req.validate(); len(validationIssues) > 0 {
+		httputil.ServeError(rw, http.StatusBadRequest, newValidationError(validationIssues))
+		return
+	}
+
+	var created reservationstore.Reservation
+
+	// The vehicle becomes reserved along with the creation of its reservation,
+	// after the end of its expired reservation which may still hold it.
+	err := h.store.WithTx(r.Context(), func(tx storage.Store) error {
+		if err := expireVehicleReservations(r.Context(), tx, req.VehicleID); err != nil {
+			return err
+		}
+
+		if _, err := tx.Vehicle().Transition(r.Context(), req.VehicleID, vehiclestore.StatusReserved); err != nil {
+			return err
+		}
+
+		var err error
+
+		created, err = tx.Reservation().Create(
+			r.Context(),
+			reservationstore.Reservation{VehicleID: req.VehicleID, RiderID: req.RiderID},
+			h.hold,
+		)
+
+		return err
+	})
+
+	var transitionErr *vehiclestore.TransitionError
+
+	switch {
+	case errors.Is(err, vehiclestore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newNotFoundError(req.VehicleID))
+		return
+	case errors.As(err, &transitionErr):
+		httputil.ServeError(rw, http.StatusConflict, newVehicleUnavailableError(req.VehicleID, transitionErr.From))
+		return
+	case errors.Is(err, reservationstore.ErrVehicleReserved):
+		httputil.ServeError(rw, http.StatusConflict, newVehicleUnavailableError(req.VehicleID, vehiclestore.StatusReserved))
+		return
+	case err != nil:
+		h.logger.Error(
+			"Could not reserve the vehicle",
+			zap.Int64("vehicle-id", req.VehicleID),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.ServeJSON(rw, http.StatusCreated, &ReservationResponse{Reservation: newReservationFromModel(created)})
+}
+
+type GetReservationHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewGetReservationHandler(store storage.Store, logger *zap.Logger) *GetReservationHandler {
+	return &GetReservationHandler{
+		store:  store,
+		logger: logger.With(zap.String("handler", "get_reservation")),
+	}
+}
+
+func (h *GetReservationHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	reservation, err := h.store.Reservation().Get(r.Context(), id)
+	switch {
+	case errors.Is(err, reservationstore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newReservationNotFoundError(id))
+		return
+	case err != nil:
+		h.logger.Error(
+			"Could not get the reservation",
+			zap.Int64("id", id),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, &ReservationResponse{Reservation: newReservationFromModel(reservation)})
+}
+
+// CancelReservationHandler ends an active reservation, making its vehicle available again.
+type CancelReservationHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewCancelReservationHandler(store storage.Store, logger *zap.Logger) *CancelReservationHandler {
+	return &CancelReservationHandler{
+		store:  store,
+		logger: logger.With(zap.String("handler", "cancel_reservation")),
+	}
+}
+
+func (h *CancelReservationHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	var canceled reservationstore.Reservation
+
+	err = h.store.WithTx(r.Context(), func(tx storage.Store) error {
+		var err error
+
+		canceled, err = tx.Reservation().Cancel(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		return releaseVehicle(r.Context(), tx, canceled.VehicleID)
+	})
+
+	switch {
+	case errors.Is(err, reservationstore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newReservationNotFoundError(id))
+		return
+	case errors.Is(err, reservationstore.ErrNotActive):
+		httputil.ServeError(rw, http.StatusConflict, newReservationEndedError(id))
+		return
+	case err != nil:
+		h.logger.Error(
+			"Could not cancel the reservation",
+			zap.Int64("id", id),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, &ReservationResponse{Reservation: newReservationFromModel(canceled)})
+}
+
+// ExpireReservations ends the reservations past their expiry and makes their vehicles available again.
+// It returns the number of expired reservations.
+func ExpireReservations(ctx context.Context, store storage.Store) (int, error) {
+	var expired int
+
+	err := store.WithTx(ctx, func(tx storage.Store) error {
+		var err error
+
+		expired, err = expireReservations(ctx, tx)
+		return err
+	})
+
+	return expired, err
+}
+
+// expireReservations is ExpireReservations, in the transaction of the caller.
+func expireReservations(ctx context.Context, tx storage.Store) (int, error) {
+	expired, err := tx.Reservation().Expire(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, reservation := range expired {
+		if err := releaseVehicle(ctx, tx, reservation.VehicleID); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(expired), nil
+}
+
+// expireVehicleReservations ends the reservations of a vehicle past their expiry
+// and makes it available again, leaving the other vehicles to ExpireReservations.
+func expireVehicleReservations(ctx context.Context, tx storage.Store, vehicleID int64) error {
+	expired, err := tx.Reservation().ExpireVehicle(ctx, vehicleID)
+	if err != nil {
+		return err
+	}
+
+	if len(expired) == 0 {
+		return nil
+	}
+
+	return releaseVehicle(ctx, tx, vehicleID)
+}
+
+// releaseVehicle makes a vehicle available at the end of its reservation.
+// The vehicles deleted or whose status changed since they were reserved are left alone.
+func releaseVehicle(ctx context.Context, tx storage.Store, vehicleID int64) error {
+	v, err := tx.Vehicle().Get(ctx, vehicleID)
+	if errors.Is(err, vehiclestore.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if v.Status != vehiclestore.StatusReserved {
+		return nil
+	}
+
+	_, err = tx.Vehicle().Transition(ctx, vehicleID, vehiclestore.StatusAvailable)
+
+	return err
+}
@@ -0,0 +1,251 @@
+//go:build !integration
+
+package vehicle_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/cursor"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/vehicle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// hold is the duration of the reservations made by the tests.
+const hold = 15 * time.Minute
+
+func TestReserveHandler(t *testing.T) {
+	for _, testCase := range []struct {
+		desc       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "available vehicle",
+			body:       `{"vehicle_id":1,"rider_id":"alice"}`,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"reservation":{"id":1,"vehicle_id":1,"rider_id":"alice","status":"active","created_at":"2024-03-01T12:30:00Z","expires_at":"2024-03-01T12:45:00Z"}}`,
+		},
+		{
+			desc:       "vehicle in maintenance",
+			body:       `{"vehicle_id":2,"rider_id":"alice"}`,
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"code":1005,"message":"The vehicle is not available","details":{"id":2,"status":"maintenance"}}`,
+		},
+		{
+			desc:       "unknown vehicle",
+			body:       `{"vehicle_id":3,"rider_id":"alice"}`,
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":1004,"message":"The vehicle does not exist","details":{"id":3}}`,
+		},
+		{
+			desc:       "missing fields",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["missing vehicle_id","missing rider_id"]}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			store := newReservableStore(t, newMemoryStore())
+
+			_, err := store.Vehicle().Transition(context.Background(), 2, vehiclestore.StatusMaintenance)
+			require.NoError(t, err)
+
+			resp := serve(vehicle.NewReserveHandler(store, hold, zap.NewNop()), http.MethodPost, "/reservations", "", testCase.body)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestReserveHandlerHidesReservedVehicles(t *testing.T) {
+	var (
+		store   = newReservableStore(t, newMemoryStore())
+		reserve = vehicle.NewReserveHandler(store, hold, zap.NewNop())
+		list    = vehicle.NewListHandler(store, cursor.NewCodec([]byte("secret")), 100, zap.NewNop())
+	)
+
+	resp := serve(reserve, http.MethodPost, "/reservations", "", `{"vehicle_id":1,"rider_id":"alice"}`)
+	require.Equal(t, http.StatusCreated, resp.Result().StatusCode)
+
+	// The vehicle cannot be reserved by another rider.
+	resp = serve(reserve, http.MethodPost, "/reservations", "", `{"vehicle_id":1,"rider_id":"bob"}`)
+	assert.Equal(t, http.StatusConflict, resp.Result().StatusCode)
+	assert.JSONEq(t, `{"code":1005,"message":"The vehicle is not available","details":{"id":1,"status":"reserved"}}`, resp.Body.String())
+
+	// Nor found by them.
+	resp = serve(list, http.MethodGet, "/vehicles?latitude=50&longitude=50", "", "")
+	assert.Equal(t, http.StatusOK, resp.Result().StatusCode)
+	assert.JSONEq(
+		t,
+		`{"vehicles":[{"id":2,"shortcode":"bbb","battery":50,"latitude":50,"longitude":50,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z","distance":0}]}`,
+		resp.Body.String(),
+	)
+}
+
+func TestCancelReservationHandler(t *testing.T) {
+	var (
+		store   = newReservableStore(t, newMemoryStore())
+		reserve = vehicle.NewReserveHandler(store, hold, zap.NewNop())
+		cancel  = vehicle.NewCancelReservationHandler(store, zap.NewNop())
+		get     = vehicle.NewGetReservationHandler(store, zap.NewNop())
+	)
+
+	resp := serve(reserve, http.MethodPost, "/reservations", "", `{"vehicle_id":1,"rider_id":"alice"}`)
+	require.Equal(t, http.StatusCreated, resp.Result().StatusCode)
+
+	resp = serve(cancel, http.MethodPost, "/reservations/1/cancel", "1", "")
+	assert.Equal(t, http.StatusOK, resp.Result().StatusCode)
+	assert.JSONEq(
+		t,
+		`{"reservation":{"id":1,"vehicle_id":1,"rider_id":"alice","status":"canceled","created_at":"2024-03-01T12:30:00Z","expires_at":"2024-03-01T12:45:00Z","ended_at":"2024-03-01T12:30:00Z"}}`,
+		resp.Body.String(),
+	)
+
+	resp = serve(get, http.MethodGet, "/reservations/1", "1", "")
+	assert.Equal(t, http.StatusOK, resp.Result().StatusCode)
+	assert.Contains(t, resp.Body.String(), `"status":"canceled"`)
+
+	v, err := store.Vehicle().Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, vehiclestore.StatusAvailable, v.Status)
+
+	resp = serve(cancel, http.MethodPost, "/reservations/1/cancel", "1", "")
+	assert.Equal(t, http.StatusConflict, resp.Result().StatusCode)
+	assert.JSONEq(t, `{"code":1005,"message":"The reservation already ended","details":{"id":1}}`, resp.Body.String())
+
+	resp = serve(cancel, http.MethodPost, "/reservations/2/cancel", "2", "")
+	assert.Equal(t, http.StatusNotFound, resp.Result().StatusCode)
+	assert.JSONEq(t, `{"code":1004,"message":"The reservation does not exist","details":{"id":2}}`, resp.Body.String())
+
+	resp = serve(get, http.MethodGet, "/reservations/2", "2", "")
+	assert.Equal(t, http.StatusNotFound, resp.Result().StatusCode)
+}
+
+func TestExpireReservations(t *testing.T) {
+	var (
+		clock   = now
+		store   = newReservableStore(t, storage.NewMemoryStoreWithClock(func() time.Time { return clock }))
+		reserve = vehicle.NewReserveHandler(store, hold, zap.NewNop())
+		get     = vehicle.NewGetReservationHandler(store, zap.NewNop())
+	)
+
+	for _, body := range []string{`{"vehicle_id":1,"rider_id":"alice"}`, `{"vehicle_id":2,"rider_id":"bob"}`} {
+		resp := serve(reserve, http.MethodPost, "/reservations", "", body)
+		require.Equal(t, http.StatusCreated, resp.Result().StatusCode)
+	}
+
+	clock = now.Add(hold)
+
+	// The lookups tell the reservation expired before it is ended.
+	resp := serve(get, http.MethodGet, "/reservations/1", "1", "")
+	assert.Equal(t, http.StatusOK, resp.Result().StatusCode)
+	assert.JSONEq(
+		t,
+		`{"reservation":{"id":1,"vehicle_id":1,"rider_id":"alice","status":"expired","created_at":"2024-03-01T12:30:00Z","expires_at":"2024-03-01T12:45:00Z","ended_at":"2024-03-01T12:45:00Z"}}`,
+		resp.Body.String(),
+	)
+
+	// Reserving ends the expired reservation of the vehicle first, the others are left to the expiry.
+	resp = serve(reserve, http.MethodPost, "/reservations", "", `{"vehicle_id":1,"rider_id":"carol"}`)
+	assert.Equal(t, http.StatusCreated, resp.Result().StatusCode)
+
+	v, err := store.Vehicle().Get(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, vehiclestore.StatusReserved, v.Status)
+
+	clock = now.Add(2 * hold)
+
+	expired, err := vehicle.ExpireReservations(context.Background(), store)
+	require.NoError(t, err)
+	assert.Equal(t, 2, expired)
+
+	for _, id := range []int64{1, 2} {
+		v, err = store.Vehicle().Get(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, vehiclestore.StatusAvailable, v.Status)
+	}
+}
+
+func TestDeleteReservedVehicle(t *testing.T) {
+	var (
+		clock   = now
+		store   = newReservableStore(t, storage.NewMemoryStoreWithClock(func() time.Time { return clock }))
+		reserve = vehicle.NewReserveHandler(store, hold, zap.NewNop())
+		del     = vehicle.NewDeleteHandler(store, zap.NewNop())
+		restore = vehicle.NewRestoreHandler(store, zap.NewNop())
+	)
+
+	resp := serve(reserve, http.MethodPost, "/reservations", "", `{"vehicle_id":1,"rider_id":"alice"}`)
+	require.Equal(t, http.StatusCreated, resp.Result().StatusCode)
+
+	// The reserved vehicle stays until its reservation ends, which releases it.
+	resp = serve(del, http.MethodDelete, "/vehicles/1", "1", "")
+	assert.Equal(t, http.StatusConflict, resp.Result().StatusCode)
+	assert.JSONEq(
+		t,
+		`{"code":1005,"message":"The vehicle is not available","details":{"id":1,"status":"reserved"}}`,
+		resp.Body.String(),
+	)
+
+	clock = now.Add(hold)
+
+	expired, err := vehicle.ExpireReservations(context.Background(), store)
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+
+	resp = serve(del, http.MethodDelete, "/vehicles/1", "1", "")
+	assert.Equal(t, http.StatusNoContent, resp.Result().StatusCode)
+
+	resp = serve(restore, http.MethodPost, "/vehicles/1/restore", "1", "")
+	assert.Equal(t, http.StatusOK, resp.Result().StatusCode)
+
+	v, err := store.Vehicle().Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, vehiclestore.StatusAvailable, v.Status)
+}
+
+// newReservableStore adds two available vehicles to the store.
+func newReservableStore(t *testing.T, store *storage.MemoryStore) *storage.MemoryStore {
+	t.Helper()
+
+	for _, shortCode := range []string{"aaa", "bbb"} {
+		_, err := store.Vehicle().Create(
+			context.Background(),
+			vehiclestore.Vehicle{ShortCode: shortCode, BatteryLevel: 50, Position: vehiclestore.Point{Latitude: 50, Longitude: 50}},
+		)
+		require.NoError(t, err)
+	}
+
+	return store
+}
+
+// serve sends a request to the handler, with the id path value and a JSON body when not empty.
+func serve(handler http.Handler, method, target, id, body string) *httptest.ResponseRecorder {
+	var reqBody io.Reader = http.NoBody
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, reqBody)
+	req.SetPathValue("id", id)
+	if body != "" {
+		req.Header.Add("Content-Type", "application/json")
+	}
+
+	handler.ServeHTTP(resp, req)
+
+	return resp
+}
@@ -0,0 +1,53 @@
+package vehicle
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"go.uber.org/zap"
+)
+
+type RestoreResponse struct {
+	Vehicle Vehicle `json:"vehicle"`
+}
+
+// RestoreHandler undoes the deletion of a vehicle, until it is purged.
+type RestoreHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewRestoreHandler(store storage.Store, logger *zap.Logger) *RestoreHandler {
+	return &RestoreHandler{
+		store:  store,
+		logger: logger.With(zap.String("handler", "restore_vehicle")),
+	}
+}
+
+func (h *RestoreHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	v, err := h.store.Vehicle().Restore(r.Context(), id)
+	switch {
+	case errors.Is(err, vehiclestore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newNotFoundError(id))
+		return
+	case err != nil:
+		h.logger.Error(
+			"Could not restore the vehicle",
+			zap.Int64("id", id),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, &RestoreResponse{Vehicle: newVehicleFromModel(v)})
+}
@@ -0,0 +1,75 @@
+//go:build !integration
+
+package vehicle_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/vehicle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRestoreHandler(t *testing.T) {
+	for _, testCase := range []struct {
+		desc       string
+		id         string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "deleted vehicle",
+			id:         "1",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"vehicle":{"id":1,"shortcode":"abcd","battery":42,"latitude":12.5,"longitude":3.2,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}}`,
+		},
+		{
+			desc:       "vehicle not deleted",
+			id:         "2",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":1004,"message":"The vehicle does not exist","details":{"id":2}}`,
+		},
+		{
+			desc:       "unknown vehicle",
+			id:         "3",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":1004,"message":"The vehicle does not exist","details":{"id":3}}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			store := newMemoryStore()
+
+			for _, shortCode := range []string{"abcd", "efgh"} {
+				_, err := store.Vehicle().Create(
+					context.Background(),
+					vehiclestore.Vehicle{
+						ShortCode:    shortCode,
+						BatteryLevel: 42,
+						Position:     vehiclestore.Point{Latitude: 12.5, Longitude: 3.2},
+					},
+				)
+				require.NoError(t, err)
+			}
+
+			deleted, err := store.Vehicle().Delete(context.Background(), 1)
+			require.NoError(t, err)
+			require.True(t, deleted)
+
+			handler := vehicle.NewRestoreHandler(store, zap.NewNop())
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/vehicles/"+testCase.id+"/restore", nil)
+			req.SetPathValue("id", testCase.id)
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package vehicle
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/telemetrystore"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultHistoryLimit is the number of records returned when the request does not set a limit.
+	defaultHistoryLimit = 100
+	// maxHistoryLimit bounds the number of records returned at once.
+	maxHistoryLimit = 1000
+)
+
+// Telemetry is the position and battery level of a vehicle at a point in time.
+type Telemetry struct {
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	BatteryLevel int64     `json:"battery"`
+	RecordedAt   time.Time `json:"recorded_at"`
+}
+
+func newTelemetryFromModel(r telemetrystore.Record) Telemetry {
+	return Telemetry{
+		Latitude:     r.Position.Latitude,
+		Longitude:    r.Position.Longitude,
+		BatteryLevel: r.BatteryLevel,
+		RecordedAt:   r.RecordedAt,
+	}
+}
+
+// newRecordFromModel records the current position and battery level of a vehicle,
+// as of its last update.
+func newRecordFromModel(v vehiclestore.Vehicle) telemetrystore.Record {
+	return telemetrystore.Record{
+		VehicleID:    v.ID,
+		Position:     v.Position,
+		BatteryLevel: v.BatteryLevel,
+		RecordedAt:   v.UpdatedAt,
+	}
+}
+
+func newTelemetryNotFoundError(id int64, at time.Time) error {
+	return &httputil.APIError{
+		Code:    httputil.ErrCodeResourceNotFound,
+		Message: "The vehicle has no telemetry at this time",
+		Details: map[string]any{"id": id, "at": at},
+	}
+}
+
+// HistoryRequest selects the records of a vehicle from a time included to another excluded.
+type HistoryRequest struct {
+	From  time.Time
+	To    time.Time
+	Limit int64
+}
+
+// newHistoryRequestFromQueryParameters parses the query parameters,
+// it returns the issues of the malformed ones, see validate for the others.
+func newHistoryRequestFromQueryParameters(query url.Values) (*HistoryRequest, []string) {
+	var (
+		req    = HistoryRequest{Limit: defaultHistoryLimit}
+		parser = queryParser{query: query}
+	)
+
+	from, hasFrom := parser.timestamp("from", "from must be a RFC 3339 timestamp")
+	to, hasTo := parser.timestamp("to", "to must be a RFC 3339 timestamp")
+
+	if !query.Has("from") || !query.Has("to") {
+		parser.issues = append(parser.issues, "missing from and to")
+	}
+
+	if hasFrom && hasTo && !from.Before(to) {
+		parser.issues = append(parser.issues, "from must be before to")
+	}
+
+	req.From, req.To = from, to
+
+	if limit, ok := parser.int("limit", "limit must be an integer"); ok {
+		req.Limit = limit
+	}
+
+	return &req, parser.issues
+}
+
+func (req *HistoryRequest) validate() []string {
+	var validationIssues []string
+
+	if req.Limit < 1 || req.Limit > maxHistoryLimit {
+		validationIssues = append(validationIssues, fmt.Sprintf("limit must be > 0 and <= %d", maxHistoryLimit))
+	}
+
+	return validationIssues
+}
+
+type HistoryResponse struct {
+	Telemetry []Telemetry `json:"telemetry"`
+}
+
+// HistoryHandler lists the telemetry of a vehicle over a time range, oldest first.
+type HistoryHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewHistoryHandler(store storage.Store, logger *zap.Logger) *HistoryHandler {
+	return &HistoryHandler{
+		store:  store,
+		logger: logger.With(zap.String("handler", "get_vehicle_history")),
+	}
+}
+
+func (h *HistoryHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	req, validationIssues := newHistoryRequestFromQueryParameters(r.URL.Query())
+	validationIssues = append(validationIssues, req.validate()...)
+	if len(validationIssues) > 0 {
+		httputil.ServeError(rw, http.StatusBadRequest, newValidationError(validationIssues))
+		return
+	}
+
+	if _, err := h.store.Vehicle().Get(r.Context(), id); err != nil {
+		serveVehicleLookupError(rw, h.logger, id, err)
+		return
+	}
+
+	records, err := h.store.Telemetry().History(r.Context(), id, req.From, req.To, req.Limit)
+	if err != nil {
+		h.logger.Error(
+			"Could not get the vehicle history",
+			zap.Int64("id", id),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := HistoryResponse{Telemetry: make([]Telemetry, len(records))}
+	for i, record := range records {
+		resp.Telemetry[i] = newTelemetryFromModel(record)
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, &resp)
+}
+
+type PositionAtResponse struct {
+	Telemetry Telemetry `json:"telemetry"`
+}
+
+// PositionAtHandler returns the last telemetry of a vehicle at or before a point in time.
+type PositionAtHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewPositionAtHandler(store storage.Store, logger *zap.Logger) *PositionAtHandler {
+	return &PositionAtHandler{
+		store:  store,
+		logger: logger.With(zap.String("handler", "get_vehicle_position_at")),
+	}
+}
+
+func (h *PositionAtHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	parser := queryParser{query: r.URL.Query()}
+
+	at, ok := parser.timestamp("at", "at must be a RFC 3339 timestamp")
+	if !ok && len(parser.issues) == 0 {
+		parser.issues = append(parser.issues, "missing at")
+	}
+	if len(parser.issues) > 0 {
+		httputil.ServeError(rw, http.StatusBadRequest, newValidationError(parser.issues))
+		return
+	}
+
+	if _, err := h.store.Vehicle().Get(r.Context(), id); err != nil {
+		serveVehicleLookupError(rw, h.logger, id, err)
+		return
+	}
+
+	record, err := h.store.Telemetry().At(r.Context(), id, at)
+	switch {
+	case errors.Is(err, telemetrystore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newTelemetryNotFoundError(id, at))
+		return
+	case err != nil:
+		h.logger.Error(
+			"Could not get the vehicle position",
+			zap.Int64("id", id),
+			zap.Time("at", at),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, &PositionAtResponse{Telemetry: newTelemetryFromModel(record)})
+}
+
+// serveVehicleLookupError serves the error of getting the vehicle a request is about.
+func serveVehicleLookupError(rw http.ResponseWriter, logger *zap.Logger, id int64, err error) {
+	if errors.Is(err, vehiclestore.ErrNotFound) {
+		httputil.ServeError(rw, http.StatusNotFound, newNotFoundError(id))
+		return
+	}
+
+	logger.Error(
+		"Could not get the vehicle from store",
+		zap.Int64("id", id),
+		zap.Error(err),
+	)
+	httputil.ServeError(rw, http.StatusInternalServerError, err)
+}
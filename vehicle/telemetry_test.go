@@ -0,0 +1,232 @@
+//go:build !integration
+
+package vehicle_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/telemetrystore"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/vehicle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTelemetryStore returns a store holding a vehicle created at now,
+// then moved an hour and two hours later.
+func newTelemetryStore(t *testing.T) storage.Store {
+	t.Helper()
+
+	store := newMemoryStore()
+
+	_, err := store.Vehicle().Create(
+		context.Background(),
+		vehiclestore.Vehicle{ShortCode: "abcd", BatteryLevel: 90, Position: vehiclestore.Point{Latitude: 12.5, Longitude: 3.2}},
+	)
+	require.NoError(t, err)
+
+	for i, r := range []telemetrystore.Record{
+		{Position: vehiclestore.Point{Latitude: 12.5, Longitude: 3.2}, BatteryLevel: 90},
+		{Position: vehiclestore.Point{Latitude: 12.6, Longitude: 3.3}, BatteryLevel: 80},
+		{Position: vehiclestore.Point{Latitude: 12.7, Longitude: 3.4}, BatteryLevel: 70},
+	} {
+		r.VehicleID = 1
+		r.RecordedAt = now.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, store.Telemetry().Append(context.Background(), r))
+	}
+
+	return store
+}
+
+func TestHistoryHandler(t *testing.T) {
+	store := newTelemetryStore(t)
+
+	for _, testCase := range []struct {
+		desc       string
+		id         string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "time range",
+			id:         "1",
+			query:      "from=2024-03-01T12:30:00Z&to=2024-03-01T14:30:00Z",
+			wantStatus: http.StatusOK,
+			wantBody: `{"telemetry":[
+				{"latitude":12.5,"longitude":3.2,"battery":90,"recorded_at":"2024-03-01T12:30:00Z"},
+				{"latitude":12.6,"longitude":3.3,"battery":80,"recorded_at":"2024-03-01T13:30:00Z"}
+			]}`,
+		},
+		{
+			desc:       "limit",
+			id:         "1",
+			query:      "from=2024-03-01T12:30:00Z&to=2024-03-01T18:00:00Z&limit=1",
+			wantStatus: http.StatusOK,
+			wantBody: `{"telemetry":[
+				{"latitude":12.5,"longitude":3.2,"battery":90,"recorded_at":"2024-03-01T12:30:00Z"}
+			]}`,
+		},
+		{
+			desc:       "no record",
+			id:         "1",
+			query:      "from=2024-03-02T00:00:00Z&to=2024-03-03T00:00:00Z",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"telemetry":[]}`,
+		},
+		{
+			desc:       "missing range",
+			id:         "1",
+			query:      "from=2024-03-02T00:00:00Z",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["missing from and to"]}`,
+		},
+		{
+			desc:       "invalid range",
+			id:         "1",
+			query:      "from=2024-03-02T00:00:00Z&to=2024-03-01T00:00:00Z&limit=5000",
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"code":1003,"message":"The request payload is invalid","details":[
+				"from must be before to",
+				"limit must be > 0 and <= 1000"
+			]}`,
+		},
+		{
+			desc:       "malformed time",
+			id:         "1",
+			query:      "from=yesterday&to=2024-03-01T00:00:00Z",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["from must be a RFC 3339 timestamp"]}`,
+		},
+		{
+			desc:       "unknown vehicle",
+			id:         "2",
+			query:      "from=2024-03-01T12:30:00Z&to=2024-03-01T14:30:00Z",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":1004,"message":"The vehicle does not exist","details":{"id":2}}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			handler := vehicle.NewHistoryHandler(store, zap.NewNop())
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/vehicles/"+testCase.id+"/telemetry/history?"+testCase.query, http.NoBody)
+			req.SetPathValue("id", testCase.id)
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestPositionAtHandler(t *testing.T) {
+	store := newTelemetryStore(t)
+
+	for _, testCase := range []struct {
+		desc       string
+		id         string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "between two records",
+			id:         "1",
+			query:      "at=2024-03-01T14:00:00Z",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"telemetry":{"latitude":12.6,"longitude":3.3,"battery":80,"recorded_at":"2024-03-01T13:30:00Z"}}`,
+		},
+		{
+			desc:       "after the last record",
+			id:         "1",
+			query:      "at=2024-03-02T00:00:00Z",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"telemetry":{"latitude":12.7,"longitude":3.4,"battery":70,"recorded_at":"2024-03-01T14:30:00Z"}}`,
+		},
+		{
+			desc:       "before the first record",
+			id:         "1",
+			query:      "at=2024-03-01T12:00:00Z",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":1004,"message":"The vehicle has no telemetry at this time","details":{"id":1,"at":"2024-03-01T12:00:00Z"}}`,
+		},
+		{
+			desc:       "missing time",
+			id:         "1",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["missing at"]}`,
+		},
+		{
+			desc:       "unknown vehicle",
+			id:         "2",
+			query:      "at=2024-03-01T14:00:00Z",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":1004,"message":"The vehicle does not exist","details":{"id":2}}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			handler := vehicle.NewPositionAtHandler(store, zap.NewNop())
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/vehicles/"+testCase.id+"/telemetry/position?"+testCase.query, http.NoBody)
+			req.SetPathValue("id", testCase.id)
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestUpdateHandlerRecordsTelemetry(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		clock = now
+		store = storage.NewMemoryStoreWithClock(func() time.Time { return clock })
+	)
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(
+		http.MethodPost,
+		"/vehicles",
+		strings.NewReader(`{"shortcode":"abcd","latitude":12.5,"longitude":3.2,"battery":90}`),
+	)
+	req.Header.Add("Content-Type", "application/json")
+
+	vehicle.NewCreateHandler(store, newShortCodeGenerator(t, "AB", nil), zap.NewNop()).ServeHTTP(resp, req)
+	require.Equal(t, http.StatusCreated, resp.Result().StatusCode)
+
+	for _, patch := range []string{`{"battery": 80}`, `{"shortcode": "efgh"}`} {
+		clock = clock.Add(time.Hour)
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/vehicles/1", strings.NewReader(patch))
+		req.Header.Add("Content-Type", "application/merge-patch+json")
+		req.SetPathValue("id", "1")
+
+		vehicle.NewUpdateHandler(store, zap.NewNop()).ServeHTTP(resp, req)
+		require.Equal(t, http.StatusOK, resp.Result().StatusCode)
+	}
+
+	// Changing the shortcode is not recorded.
+	history, err := store.Telemetry().History(ctx, 1, now, clock.Add(time.Hour), 0)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]telemetrystore.Record{
+			{VehicleID: 1, Position: vehiclestore.Point{Latitude: 12.5, Longitude: 3.2}, BatteryLevel: 90, RecordedAt: now},
+			{VehicleID: 1, Position: vehiclestore.Point{Latitude: 12.5, Longitude: 3.2}, BatteryLevel: 80, RecordedAt: now.Add(time.Hour)},
+		},
+		history,
+	)
+}
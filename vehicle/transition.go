@@ -0,0 +1,122 @@
+package vehicle
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"go.uber.org/zap"
+)
+
+// operatorStatuses are the statuses changed through the transitions, the reserved and
+// in use vehicles only enter and leave them through the reservations and the trips.
+var operatorStatuses = []vehiclestore.Status{
+	vehiclestore.StatusAvailable,
+	vehiclestore.StatusMaintenance,
+	vehiclestore.StatusRetired,
+}
+
+// TransitionRequest changes the status of a vehicle, following the transition graph.
+type TransitionRequest struct {
+	Status string `json:"status"`
+}
+
+func (t *TransitionRequest) validate() []string {
+	if t.Status == "" {
+		return []string{"missing status"}
+	}
+
+	if !slices.Contains(operatorStatuses, vehiclestore.Status(t.Status)) {
+		return []string{"status must be one of " + joinStatuses(operatorStatuses)}
+	}
+
+	return nil
+}
+
+type TransitionResponse struct {
+	Vehicle Vehicle `json:"vehicle"`
+}
+
+type TransitionHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewTransitionHandler(store storage.Store, logger *zap.Logger) *TransitionHandler {
+	return &TransitionHandler{
+		store:  store,
+		logger: logger.With(zap.String("handler", "transition_vehicle")),
+	}
+}
+
+func (h *TransitionHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	var req TransitionRequest
+
+	if err := httputil.DecodeRequestAsJSON(r, &req); err != nil {
+		h.logger.Error(
+			"Could not decode request body",
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	if validationIssues := req.validate(); len(validationIssues) > 0 {
+		httputil.ServeError(rw, http.StatusBadRequest, newValidationError(validationIssues))
+		return
+	}
+
+	to := vehiclestore.Status(req.Status)
+
+	// The change only applies if the vehicle is still in the status it is read with,
+	// so that a concurrent reservation or trip is not overridden.
+	v, err := h.store.Vehicle().Get(r.Context(), id)
+	if err == nil {
+		if !slices.Contains(operatorStatuses, v.Status) {
+			err = &vehiclestore.TransitionError{From: v.Status, To: to}
+		} else {
+			v, err = h.store.Vehicle().TransitionFrom(r.Context(), id, v.Status, to)
+		}
+	}
+
+	var transitionErr *vehiclestore.TransitionError
+
+	switch {
+	case errors.Is(err, vehiclestore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newNotFoundError(id))
+		return
+	case errors.As(err, &transitionErr):
+		httputil.ServeError(rw, http.StatusConflict, newTransitionError(transitionErr))
+		return
+	case err != nil:
+		h.logger.Error(
+			"Could not change the vehicle status",
+			zap.Int64("id", id),
+			zap.String("status", req.Status),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, &TransitionResponse{Vehicle: newVehicleFromModel(v)})
+}
+
+func joinStatuses(statuses []vehiclestore.Status) string {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+
+	return strings.Join(names, ", ")
+}
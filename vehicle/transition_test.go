@@ -0,0 +1,115 @@
+//go:build !integration
+
+package vehicle_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/vehicle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTransitionHandler(t *testing.T) {
+	for _, testCase := range []struct {
+		desc       string
+		id         string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "allowed transition",
+			id:         "1",
+			body:       `{"status": "maintenance"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"vehicle":{"id":1,"shortcode":"abcd","battery":42,"latitude":12.5,"longitude":3.2,"type":"scooter","status":"maintenance","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}}`,
+		},
+		{
+			desc:       "rejected transition",
+			id:         "2",
+			body:       `{"status": "available"}`,
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"code":1006,"message":"The vehicle status cannot change to the requested one","details":{"from":"retired","to":"available"}}`,
+		},
+		{
+			desc:       "unknown status",
+			id:         "1",
+			body:       `{"status": "broken"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["status must be one of available, maintenance, retired"]}`,
+		},
+		{
+			desc:       "entering a reservation",
+			id:         "1",
+			body:       `{"status": "reserved"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["status must be one of available, maintenance, retired"]}`,
+		},
+		{
+			desc:       "leaving a trip",
+			id:         "3",
+			body:       `{"status": "available"}`,
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"code":1006,"message":"The vehicle status cannot change to the requested one","details":{"from":"in_use","to":"available"}}`,
+		},
+		{
+			desc:       "missing status",
+			id:         "1",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["missing status"]}`,
+		},
+		{
+			desc:       "unknown vehicle",
+			id:         "4",
+			body:       `{"status": "maintenance"}`,
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":1004,"message":"The vehicle does not exist","details":{"id":4}}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			store := newMemoryStore()
+
+			for _, shortCode := range []string{"abcd", "efgh", "ijkl"} {
+				_, err := store.Vehicle().Create(
+					context.Background(),
+					vehiclestore.Vehicle{
+						ShortCode:    shortCode,
+						BatteryLevel: 42,
+						Position:     vehiclestore.Point{Latitude: 12.5, Longitude: 3.2},
+					},
+				)
+				require.NoError(t, err)
+			}
+
+			_, err := store.Vehicle().Transition(context.Background(), 2, vehiclestore.StatusRetired)
+			require.NoError(t, err)
+
+			_, err = store.Vehicle().Transition(context.Background(), 3, vehiclestore.StatusInUse)
+			require.NoError(t, err)
+
+			handler := vehicle.NewTransitionHandler(store, zap.NewNop())
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPost,
+				"/vehicles/"+testCase.id+"/transitions",
+				strings.NewReader(testCase.body),
+			)
+			req.Header.Add("Content-Type", "application/json")
+			req.SetPathValue("id", testCase.id)
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
@@ -0,0 +1,453 @@
+package vehicle
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/pkg/pricing"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/reservationstore"
+	"github.com/Cirederf1/vehicle-server/storage/tripstore"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"go.uber.org/zap"
+)
+
+// errReservationMismatch is returned when a trip starts with the reservation of another vehicle or rider.
+var errReservationMismatch = errors.New("reservation of another vehicle or rider")
+
+// TripEndpoint is where and when a trip starts or ends.
+type TripEndpoint struct {
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	BatteryLevel int64     `json:"battery"`
+	At           time.Time `json:"at"`
+}
+
+func newTripEndpointFromModel(e tripstore.Endpoint) TripEndpoint {
+	return TripEndpoint{
+		Latitude:     e.Position.Latitude,
+		Longitude:    e.Position.Longitude,
+		BatteryLevel: e.BatteryLevel,
+		At:           e.At,
+	}
+}
+
+// Trip is the ride of a vehicle by a rider, its end is omitted while it is ongoing.
+type Trip struct {
+	ID        int64         `json:"id"`
+	VehicleID int64         `json:"vehicle_id"`
+	RiderID   string        `json:"rider_id"`
+	Start     TripEndpoint  `json:"start"`
+	End       *TripEndpoint `json:"end,omitempty"`
+	// Distance is in meters.
+	Distance        *float64 `json:"distance,omitempty"`
+	BatteryConsumed *int64   `json:"battery_consumed,omitempty"`
+	Fare            *Fare    `json:"fare,omitempty"`
+}
+
+func newTripFromModel(t tripstore.Trip) Trip {
+	trip := Trip{
+		ID:        t.ID,
+		VehicleID: t.VehicleID,
+		RiderID:   t.RiderID,
+		Start:     newTripEndpointFromModel(t.Start),
+	}
+
+	if t.End != nil {
+		var (
+			end             = newTripEndpointFromModel(*t.End)
+			batteryConsumed = t.BatteryConsumed()
+		)
+
+		trip.End = &end
+		trip.Distance = &t.Distance
+		trip.BatteryConsumed = &batteryConsumed
+	}
+
+	if t.Fare != nil {
+		fare := newFareFromModel(*t.Fare)
+		trip.Fare = &fare
+	}
+
+	return trip
+}
+
+type TripResponse struct {
+	Trip Trip `json:"trip"`
+}
+
+// StartTripRequest starts a trip with an available vehicle,
+// or with a reserved one given the reservation of the rider.
+type StartTripRequest struct {
+	VehicleID     int64  `json:"vehicle_id"`
+	RiderID       string `json:"rider_id"`
+	ReservationID int64  `json:"reservation_id,omitempty"`
+}
+
+func (f *StartTripRequest) validate() []string {
+	var validationIssues []string
+
+	if f.VehicleID == 0 {
+		validationIssues = append(validationIssues, "missing vehicle_id")
+	}
+
+	if f.RiderID == "" {
+		validationIssues = append(validationIssues, "missing rider_id")
+	}
+
+	return validationIssues
+}
+
+// StartTripHandler starts a trip, the vehicle is in use until it ends.
+type StartTripHandler struct {
+	store storage.Store
+	// now tells the time of the start of the trips.
+	now    func() time.Time
+	logger *zap.Logger
+}
+
+func NewStartTripHandler(store storage.Store, now func() time.Time, logger *zap.Logger) *StartTripHandler {
+	return &StartTripHandler{
+		store:  store,
+		now:    now,
+		logger: logger.With(zap.String("handler", "start_trip")),
+	}
+}
+
+func (h *StartTripHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var req StartTripRequest
+
+	if err := httputil.DecodeRequestAsJSON(r, &req); err != nil {
+		h.logger.Error(
+			"Could not decode request body",
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	if validationIssues := req.validate(); len(validationIssues) > 0 {
+		httputil.ServeError(rw, http.StatusBadRequest, newValidationError(validationIssues))
+		return
+	}
+
+	var started tripstore.Trip
+
+	// The vehicle becomes in use along with the start of the trip, and the end of its reservation.
+	err := h.store.WithTx(r.Context(), func(tx storage.Store) error {
+		from := vehiclestore.StatusAvailable
+
+		if req.ReservationID != 0 {
+			reservation, err := tx.Reservation().Get(r.Context(), req.ReservationID)
+			if err != nil {
+				return err
+			}
+
+			if reservation.VehicleID != req.VehicleID || reservation.RiderID != req.RiderID {
+				return errReservationMismatch
+			}
+
+			if _, err := tx.Reservation().Fulfill(r.Context(), req.ReservationID); err != nil {
+				return err
+			}
+
+			from = vehiclestore.StatusReserved
+		} else if err := expireVehicleReservations(r.Context(), tx, req.VehicleID); err != nil {
+			return err
+		}
+
+		v, err := tx.Vehicle().TransitionFrom(r.Context(), req.VehicleID, from, vehiclestore.StatusInUse)
+		if err != nil {
+			return err
+		}
+
+		started, err = tx.Trip().Start(
+			r.Context(),
+			tripstore.Trip{
+				VehicleID: req.VehicleID,
+				RiderID:   req.RiderID,
+				Start: tripstore.Endpoint{
+					Position:     v.Position,
+					BatteryLevel: v.BatteryLevel,
+					At:           h.now().UTC(),
+				},
+			},
+		)
+
+		return err
+	})
+
+	var transitionErr *vehiclestore.TransitionError
+
+	switch {
+	case errors.Is(err, reservationstore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newReservationNotFoundError(req.ReservationID))
+		return
+	case errors.Is(err, errReservationMismatch):
+		httputil.ServeError(rw, http.StatusConflict, newReservationMismatchError(req.ReservationID))
+		return
+	case errors.Is(err, reservationstore.ErrNotActive):
+		httputil.ServeError(rw, http.StatusConflict, newReservationEndedError(req.ReservationID))
+		return
+	case errors.Is(err, vehiclestore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newNotFoundError(req.VehicleID))
+		return
+	case errors.As(err, &transitionErr):
+		httputil.ServeError(rw, http.StatusConflict, newVehicleUnavailableError(req.VehicleID, transitionErr.From))
+		return
+	case errors.Is(err, tripstore.ErrVehicleInTrip):
+		httputil.ServeError(rw, http.StatusConflict, newVehicleUnavailableError(req.VehicleID, vehiclestore.StatusInUse))
+		return
+	case err != nil:
+		h.logger.Error(
+			"Could not start the trip",
+			zap.Int64("vehicle-id", req.VehicleID),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.ServeJSON(rw, http.StatusCreated, &TripResponse{Trip: newTripFromModel(started)})
+}
+
+// EndTripHandler ends a trip where its vehicle stands, prices it with the tariff of the vehicle type,
+// and makes the vehicle available again.
+type EndTripHandler struct {
+	store  storage.Store
+	pricer *pricing.Pricer
+	// now tells the time of the end of the trips.
+	now    func() time.Time
+	logger *zap.Logger
+}
+
+func NewEndTripHandler(store storage.Store, pricer *pricing.Pricer, now func() time.Time, logger *zap.Logger) *EndTripHandler {
+	return &EndTripHandler{
+		store:  store,
+		pricer: pricer,
+		now:    now,
+		logger: logger.With(zap.String("handler", "end_trip")),
+	}
+}
+
+func (h *EndTripHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	var (
+		ended     tripstore.Trip
+		vehicleID int64
+	)
+
+	// The distance is the one of the path through the telemetry of the vehicle during the trip.
+	err = h.store.WithTx(r.Context(), func(tx storage.Store) error {
+		trip, err := tx.Trip().Get(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		if trip.End != nil {
+			return tripstore.ErrEnded
+		}
+
+		vehicleID = trip.VehicleID
+
+		v, err := tx.Vehicle().Get(r.Context(), vehicleID)
+		if err != nil {
+			return err
+		}
+
+		end := tripstore.Endpoint{
+			Position:     v.Position,
+			BatteryLevel: v.BatteryLevel,
+			At:           h.now().UTC(),
+		}
+
+		records, err := tx.Telemetry().History(r.Context(), vehicleID, trip.Start.At, end.At, 0)
+		if err != nil {
+			return err
+		}
+
+		path := make([]vehiclestore.Point, 0, len(records)+2)
+		path = append(path, trip.Start.Position)
+		for _, record := range records {
+			path = append(path, record.Position)
+		}
+		path = append(path, end.Position)
+
+		distance := vehiclestore.PathLength(path)
+
+		fare, err := h.pricer.Fare(
+			string(v.Type),
+			pricing.Ride{Start: trip.Start.At, Duration: end.At.Sub(trip.Start.At), Distance: distance},
+		)
+		if err != nil {
+			return err
+		}
+
+		// Ending the trip guards against the concurrent ends, the vehicle is only released by the one which succeeds.
+		ended, err = tx.Trip().End(r.Context(), id, end, distance, fare)
+		if err != nil {
+			return err
+		}
+
+		// The vehicles whose status changed during the trip are left alone.
+		_, err = tx.Vehicle().TransitionFrom(r.Context(), vehicleID, vehiclestore.StatusInUse, vehiclestore.StatusAvailable)
+		if errors.Is(err, vehiclestore.ErrInvalidTransition) {
+			return nil
+		}
+
+		return err
+	})
+
+	switch {
+	case errors.Is(err, tripstore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newTripNotFoundError(id))
+		return
+	case errors.Is(err, tripstore.ErrEnded):
+		httputil.ServeError(rw, http.StatusConflict, newTripEndedError(id))
+		return
+	case errors.Is(err, vehiclestore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newNotFoundError(vehicleID))
+		return
+	case err != nil:
+		h.logger.Error(
+			"Could not end the trip",
+			zap.Int64("id", id),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, &TripResponse{Trip: newTripFromModel(ended)})
+}
+
+type GetTripHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewGetTripHandler(store storage.Store, logger *zap.Logger) *GetTripHandler {
+	return &GetTripHandler{
+		store:  store,
+		logger: logger.With(zap.String("handler", "get_trip")),
+	}
+}
+
+func (h *GetTripHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	trip, err := h.store.Trip().Get(r.Context(), id)
+	switch {
+	case errors.Is(err, tripstore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newTripNotFoundError(id))
+		return
+	case err != nil:
+		h.logger.Error(
+			"Could not get the trip",
+			zap.Int64("id", id),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, &TripResponse{Trip: newTripFromModel(trip)})
+}
+
+// TripHistoryRequest selects the trips of a vehicle, of a rider or of both,
+// started from a time included to another excluded.
+type TripHistoryRequest struct {
+	HistoryRequest
+	VehicleID int64
+	RiderID   string
+}
+
+// newTripHistoryRequestFromQueryParameters parses the query parameters,
+// it returns the issues of the malformed ones, see validate for the others.
+func newTripHistoryRequestFromQueryParameters(query url.Values) (*TripHistoryRequest, []string) {
+	history, issues := newHistoryRequestFromQueryParameters(query)
+
+	var (
+		req    = TripHistoryRequest{HistoryRequest: *history, RiderID: query.Get("rider_id")}
+		parser = queryParser{query: query, issues: issues}
+	)
+
+	if vehicleID, ok := parser.int("vehicle_id", "vehicle_id must be an integer"); ok {
+		req.VehicleID = vehicleID
+	}
+
+	return &req, parser.issues
+}
+
+func (req *TripHistoryRequest) validate() []string {
+	validationIssues := req.HistoryRequest.validate()
+
+	if req.VehicleID == 0 && req.RiderID == "" {
+		validationIssues = append(validationIssues, "missing vehicle_id or rider_id")
+	}
+
+	return validationIssues
+}
+
+type TripHistoryResponse struct {
+	Trips []Trip `json:"trips"`
+}
+
+// TripHistoryHandler lists the trips of a vehicle or a rider over a time range, oldest first.
+type TripHistoryHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewTripHistoryHandler(store storage.Store, logger *zap.Logger) *TripHistoryHandler {
+	return &TripHistoryHandler{
+		store:  store,
+		logger: logger.With(zap.String("handler", "list_trips")),
+	}
+}
+
+func (h *TripHistoryHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	req, validationIssues := newTripHistoryRequestFromQueryParameters(r.URL.Query())
+	validationIssues = append(validationIssues, req.validate()...)
+	if len(validationIssues) > 0 {
+		httputil.ServeError(rw, http.StatusBadRequest, newValidationError(validationIssues))
+		return
+	}
+
+	trips, err := h.store.Trip().History(
+		r.Context(),
+		tripstore.Filter{VehicleID: req.VehicleID, RiderID: req.RiderID},
+		req.From,
+		req.To,
+		req.Limit,
+	)
+	if err != nil {
+		h.logger.Error(
+			"Could not list the trips",
+			zap.Int64("vehicle-id", req.VehicleID),
+			zap.String("rider-id", req.RiderID),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := TripHistoryResponse{Trips: make([]Trip, len(trips))}
+	for i, trip := range trips {
+		resp.Trips[i] = newTripFromModel(trip)
+	}
+
+	httputil.ServeJSON(rw, http.StatusOK, &resp)
+}
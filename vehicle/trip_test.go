@@ -0,0 +1,241 @@
+//go:build !integration
+
+package vehicle_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/storage/reservationstore"
+	"github.com/Cirederf1/vehicle-server/storage/telemetrystore"
+	"github.com/Cirederf1/vehicle-server/storage/tripstore"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/vehicle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTripHandlers(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		clock = now
+		store = newReservableStore(t, newMemoryStore())
+		start = vehicle.NewStartTripHandler(store, func() time.Time { return clock }, zap.NewNop())
+		end   = vehicle.NewEndTripHandler(store, newPricer(t), func() time.Time { return clock }, zap.NewNop())
+		get   = vehicle.NewGetTripHandler(store, zap.NewNop())
+	)
+
+	resp := serve(start, http.MethodPost, "/trips", "", `{"vehicle_id":1,"rider_id":"alice"}`)
+	assert.Equal(t, http.StatusCreated, resp.Result().StatusCode)
+	assert.JSONEq(
+		t,
+		`{"trip":{"id":1,"vehicle_id":1,"rider_id":"alice","start":{"latitude":50,"longitude":50,"battery":50,"at":"2024-03-01T12:30:00Z"}}}`,
+		resp.Body.String(),
+	)
+
+	v, err := store.Vehicle().Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, vehiclestore.StatusInUse, v.Status)
+
+	// The vehicle cannot start another trip.
+	resp = serve(start, http.MethodPost, "/trips", "", `{"vehicle_id":1,"rider_id":"bob"}`)
+	assert.Equal(t, http.StatusConflict, resp.Result().StatusCode)
+	assert.JSONEq(t, `{"code":1005,"message":"The vehicle is not available","details":{"id":1,"status":"in_use"}}`, resp.Body.String())
+
+	// Nor be deleted before the end of the trip.
+	resp = serve(vehicle.NewDeleteHandler(store, zap.NewNop()), http.MethodDelete, "/vehicles/1", "1", "")
+	assert.Equal(t, http.StatusConflict, resp.Result().StatusCode)
+	assert.JSONEq(t, `{"code":1005,"message":"The vehicle is not available","details":{"id":1,"status":"in_use"}}`, resp.Body.String())
+
+	// The vehicle goes north during the trip, the record of the other vehicle does not count.
+	require.NoError(t, store.Telemetry().AppendBatch(ctx, []telemetrystore.Record{
+		{VehicleID: 1, Position: vehiclestore.Point{Latitude: 50.001, Longitude: 50}, BatteryLevel: 46, RecordedAt: now.Add(time.Minute)},
+		{VehicleID: 2, Position: vehiclestore.Point{Latitude: 10, Longitude: 10}, BatteryLevel: 46, RecordedAt: now.Add(time.Minute)},
+		{VehicleID: 1, Position: vehiclestore.Point{Latitude: 50.002, Longitude: 50}, BatteryLevel: 42, RecordedAt: now.Add(2 * time.Minute)},
+	}))
+
+	_, err = store.Vehicle().ReportPositions(ctx, []vehiclestore.PositionReport{
+		{ID: 1, Position: vehiclestore.Point{Latitude: 50.002, Longitude: 50}, BatteryLevel: 42, RecordedAt: now.Add(2 * time.Minute)},
+	})
+	require.NoError(t, err)
+
+	clock = now.Add(5 * time.Minute)
+
+	resp = serve(end, http.MethodPost, "/trips/1/end", "1", "")
+	require.Equal(t, http.StatusOK, resp.Result().StatusCode)
+
+	var ended vehicle.TripResponse
+	require.NoError(t, httputil.DecodeJSON(resp.Result().Body, &ended))
+	assert.Equal(t, &vehicle.TripEndpoint{Latitude: 50.002, Longitude: 50, BatteryLevel: 42, At: now.Add(5 * time.Minute)}, ended.Trip.End)
+	require.NotNil(t, ended.Trip.Distance)
+	assert.InDelta(t, 222.4, *ended.Trip.Distance, 0.1)
+	assert.Equal(t, int64(8), *ended.Trip.BatteryConsumed)
+	assert.Equal(
+		t,
+		&vehicle.Fare{
+			Currency: "EUR",
+			Items: []vehicle.FareItem{
+				{Kind: "unlock", Amount: 100},
+				{Kind: "time", Amount: 125},
+				{Kind: "distance", Amount: 2},
+			},
+			Total: 227,
+		},
+		ended.Trip.Fare,
+	)
+
+	v, err = store.Vehicle().Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, vehiclestore.StatusAvailable, v.Status)
+
+	resp = serve(get, http.MethodGet, "/trips/1", "1", "")
+	assert.Equal(t, http.StatusOK, resp.Result().StatusCode)
+
+	var got vehicle.TripResponse
+	require.NoError(t, httputil.DecodeJSON(resp.Result().Body, &got))
+	assert.Equal(t, ended, got)
+
+	resp = serve(end, http.MethodPost, "/trips/1/end", "1", "")
+	assert.Equal(t, http.StatusConflict, resp.Result().StatusCode)
+	assert.JSONEq(t, `{"code":1005,"message":"The trip already ended","details":{"id":1}}`, resp.Body.String())
+
+	resp = serve(end, http.MethodPost, "/trips/2/end", "2", "")
+	assert.Equal(t, http.StatusNotFound, resp.Result().StatusCode)
+	assert.JSONEq(t, `{"code":1004,"message":"The trip does not exist","details":{"id":2}}`, resp.Body.String())
+
+	resp = serve(get, http.MethodGet, "/trips/2", "2", "")
+	assert.Equal(t, http.StatusNotFound, resp.Result().StatusCode)
+}
+
+func TestStartTripHandlerWithReservation(t *testing.T) {
+	var (
+		store   = newReservableStore(t, newMemoryStore())
+		reserve = vehicle.NewReserveHandler(store, hold, zap.NewNop())
+		start   = vehicle.NewStartTripHandler(store, func() time.Time { return now }, zap.NewNop())
+	)
+
+	resp := serve(reserve, http.MethodPost, "/reservations", "", `{"vehicle_id":1,"rider_id":"alice"}`)
+	require.Equal(t, http.StatusCreated, resp.Result().StatusCode)
+
+	for _, testCase := range []struct {
+		desc       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "without the reservation",
+			body:       `{"vehicle_id":1,"rider_id":"bob"}`,
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"code":1005,"message":"The vehicle is not available","details":{"id":1,"status":"reserved"}}`,
+		},
+		{
+			desc:       "reservation of another rider",
+			body:       `{"vehicle_id":1,"rider_id":"bob","reservation_id":1}`,
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"code":1005,"message":"The reservation is for another vehicle or rider","details":{"id":1}}`,
+		},
+		{
+			desc:       "reservation of another vehicle",
+			body:       `{"vehicle_id":2,"rider_id":"alice","reservation_id":1}`,
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"code":1005,"message":"The reservation is for another vehicle or rider","details":{"id":1}}`,
+		},
+		{
+			desc:       "unknown reservation",
+			body:       `{"vehicle_id":1,"rider_id":"alice","reservation_id":2}`,
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":1004,"message":"The reservation does not exist","details":{"id":2}}`,
+		},
+		{
+			desc:       "reservation of the rider",
+			body:       `{"vehicle_id":1,"rider_id":"alice","reservation_id":1}`,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"trip":{"id":1,"vehicle_id":1,"rider_id":"alice","start":{"latitude":50,"longitude":50,"battery":50,"at":"2024-03-01T12:30:00Z"}}}`,
+		},
+		{
+			desc:       "fulfilled reservation",
+			body:       `{"vehicle_id":1,"rider_id":"alice","reservation_id":1}`,
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"code":1005,"message":"The reservation already ended","details":{"id":1}}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			resp := serve(start, http.MethodPost, "/trips", "", testCase.body)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+
+	reservation, err := store.Reservation().Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, reservationstore.StatusFulfilled, reservation.Status)
+}
+
+func TestTripHistoryHandler(t *testing.T) {
+	store := newReservableStore(t, newMemoryStore())
+
+	for _, trip := range []tripstore.Trip{
+		{VehicleID: 1, RiderID: "alice", Start: tripstore.Endpoint{Position: vehiclestore.Point{Latitude: 50, Longitude: 50}, BatteryLevel: 50, At: now}},
+		{VehicleID: 2, RiderID: "alice", Start: tripstore.Endpoint{Position: vehiclestore.Point{Latitude: 50, Longitude: 50}, BatteryLevel: 50, At: now.Add(time.Hour)}},
+	} {
+		_, err := store.Trip().Start(context.Background(), trip)
+		require.NoError(t, err)
+	}
+
+	for _, testCase := range []struct {
+		desc       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "trips of a vehicle",
+			query:      "vehicle_id=2&from=2024-03-01T12:00:00Z&to=2024-03-02T00:00:00Z",
+			wantStatus: http.StatusOK,
+			wantBody: `{"trips":[
+				{"id":2,"vehicle_id":2,"rider_id":"alice","start":{"latitude":50,"longitude":50,"battery":50,"at":"2024-03-01T13:30:00Z"}}
+			]}`,
+		},
+		{
+			desc:       "trips of a rider",
+			query:      "rider_id=alice&from=2024-03-01T12:00:00Z&to=2024-03-02T00:00:00Z&limit=1",
+			wantStatus: http.StatusOK,
+			wantBody: `{"trips":[
+				{"id":1,"vehicle_id":1,"rider_id":"alice","start":{"latitude":50,"longitude":50,"battery":50,"at":"2024-03-01T12:30:00Z"}}
+			]}`,
+		},
+		{
+			desc:       "no trips",
+			query:      "rider_id=bob&from=2024-03-01T12:00:00Z&to=2024-03-02T00:00:00Z",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"trips":[]}`,
+		},
+		{
+			desc:       "missing parameters",
+			query:      "",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["missing from and to","missing vehicle_id or rider_id"]}`,
+		},
+		{
+			desc:       "malformed vehicle",
+			query:      "vehicle_id=one&from=2024-03-01T12:00:00Z&to=2024-03-02T00:00:00Z",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"code":1003,"message":"The request payload is invalid","details":["vehicle_id must be an integer","missing vehicle_id or rider_id"]}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			handler := vehicle.NewTripHistoryHandler(store, zap.NewNop())
+
+			resp := serve(handler, http.MethodGet, "/trips?"+testCase.query, "", "")
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
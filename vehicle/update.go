@@ -0,0 +1,154 @@
+package vehicle
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Cirederf1/vehicle-server/pkg/httputil"
+	"github.com/Cirederf1/vehicle-server/storage"
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"go.uber.org/zap"
+)
+
+// UpdateRequest is a JSON Merge Patch applied to the CreateRequest
+// representation of an existing vehicle.
+type UpdateRequest map[string]json.RawMessage
+
+// patchableFields lists the fields an UpdateRequest may change.
+var patchableFields = []string{"latitude", "longitude", "shortcode", "battery", "type"}
+
+// apply merges the patch into req and returns the validation issues of the result.
+func (p UpdateRequest) apply(req *CreateRequest) []string {
+	var validationIssues []string
+
+	// In a merge patch null removes a member, only the attributes are optional.
+	for _, field := range patchableFields {
+		if value, ok := p[field]; ok && string(value) == "null" {
+			validationIssues = append(validationIssues, field+" cannot be removed")
+		}
+	}
+
+	if _, ok := p["status"]; ok {
+		validationIssues = append(validationIssues, "status can only change through the transitions")
+	}
+
+	if len(validationIssues) > 0 {
+		return validationIssues
+	}
+
+	// All the patchable fields are scalars, so merging boils down
+	// to decoding the patch on top of the current values.
+	encoded, err := json.Marshal(p)
+	if err != nil {
+		return []string{"invalid patch document"}
+	}
+
+	if err := json.Unmarshal(encoded, req); err != nil {
+		return []string{"invalid patch document"}
+	}
+
+	// Unlike the creations, the updates do not draw a shortcode when it is empty.
+	if req.ShortCode == "" {
+		validationIssues = append(validationIssues, "missing short code")
+	}
+
+	return append(validationIssues, req.validate()...)
+}
+
+type UpdateResponse struct {
+	Vehicle Vehicle `json:"vehicle"`
+}
+
+type UpdateHandler struct {
+	store  storage.Store
+	logger *zap.Logger
+}
+
+func NewUpdateHandler(store storage.Store, logger *zap.Logger) *UpdateHandler {
+	return &UpdateHandler{
+		store:  store,
+		logger: logger.With(zap.String("handler", "update_vehicle")),
+	}
+}
+
+func (u *UpdateHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	id, err := parseIDFromPath(r)
+	if err != nil {
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	var patch UpdateRequest
+
+	if err := httputil.DecodeRequestAsMergePatch(r, &patch); err != nil {
+		u.logger.Error(
+			"Could not decode request body",
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	var (
+		req            CreateRequest
+		updatedVehicle vehiclestore.Vehicle
+	)
+
+	// Read, merge and write the vehicle in a single transaction,
+	// along with the telemetry record of the new position or battery level.
+	err = u.store.WithTx(r.Context(), func(tx storage.Store) error {
+		current, err := tx.Vehicle().Get(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		req = newCreateRequestFromModel(current)
+
+		if validationIssues := patch.apply(&req); len(validationIssues) > 0 {
+			return newValidationError(validationIssues)
+		}
+
+		patched := req.model()
+		patched.ID = id
+
+		updatedVehicle, err = tx.Vehicle().Update(r.Context(), patched)
+		if err != nil {
+			return err
+		}
+
+		if updatedVehicle.Position == current.Position && updatedVehicle.BatteryLevel == current.BatteryLevel {
+			return nil
+		}
+
+		return tx.Telemetry().Append(r.Context(), newRecordFromModel(updatedVehicle))
+	})
+
+	var apiError *httputil.APIError
+
+	switch {
+	case errors.Is(err, vehiclestore.ErrNotFound):
+		httputil.ServeError(rw, http.StatusNotFound, newNotFoundError(id))
+		return
+	case errors.Is(err, vehiclestore.ErrDuplicateShortCode):
+		httputil.ServeError(rw, http.StatusConflict, newShortCodeConflictError(req.ShortCode))
+		return
+	case errors.As(err, &apiError):
+		httputil.ServeError(rw, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		u.logger.Error(
+			"Could not update the vehicle",
+			zap.Int64("id", id),
+			zap.Error(err),
+		)
+		httputil.ServeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	httputil.ServeJSON(
+		rw,
+		http.StatusOK,
+		&UpdateResponse{Vehicle: newVehicleFromModel(updatedVehicle)},
+	)
+}
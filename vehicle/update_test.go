@@ -0,0 +1,165 @@
+//go:build !integration
+
+package vehicle_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+	"github.com/Cirederf1/vehicle-server/vehicle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestUpdateHandler(t *testing.T) {
+	for _, testCase := range []struct {
+		desc        string
+		id          string
+		contentType string
+		patch       string
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			desc:        "partial update",
+			id:          "1",
+			contentType: "application/merge-patch+json",
+			patch:       `{"battery": 12, "latitude": 1.5}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"vehicle":{"id":1,"shortcode":"abcd","battery":12,"latitude":1.5,"longitude":3.2,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}}`,
+		},
+		{
+			desc:        "longitude beyond 90",
+			id:          "1",
+			contentType: "application/merge-patch+json",
+			patch:       `{"longitude": 150.5}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"vehicle":{"id":1,"shortcode":"abcd","battery":42,"latitude":12.5,"longitude":150.5,"type":"scooter","status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}}`,
+		},
+		{
+			desc:        "longitude above 180",
+			id:          "1",
+			contentType: "application/merge-patch+json",
+			patch:       `{"longitude": 180.5}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"code":1003,"message":"The request payload is invalid","details":["longitude must be >= -180 and <= 180"]}`,
+		},
+		{
+			desc:        "removing a field",
+			id:          "1",
+			contentType: "application/merge-patch+json",
+			patch:       `{"shortcode": null}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"code":1003,"message":"The request payload is invalid","details":["shortcode cannot be removed"]}`,
+		},
+		{
+			desc:        "emptying the shortcode",
+			id:          "1",
+			contentType: "application/merge-patch+json",
+			patch:       `{"shortcode": ""}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"code":1003,"message":"The request payload is invalid","details":["missing short code"]}`,
+		},
+		{
+			desc:        "changing the type",
+			id:          "1",
+			contentType: "application/merge-patch+json",
+			patch:       `{"type": "moped", "helmet_box": false, "seats": 2}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"vehicle":{"id":1,"shortcode":"abcd","battery":42,"latitude":12.5,"longitude":3.2,"type":"moped","helmet_box":false,"seats":2,"status":"available","created_at":"2024-03-01T12:30:00Z","updated_at":"2024-03-01T12:30:00Z"}}`,
+		},
+		{
+			desc:        "changing the type without its attributes",
+			id:          "1",
+			contentType: "application/merge-patch+json",
+			patch:       `{"type": "moped"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"code":1003,"message":"The request payload is invalid","details":["missing helmet_box for a moped"]}`,
+		},
+		{
+			desc:        "changing the status",
+			id:          "1",
+			contentType: "application/merge-patch+json",
+			patch:       `{"status": "retired"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"code":1003,"message":"The request payload is invalid","details":["status can only change through the transitions"]}`,
+		},
+		{
+			desc:        "merged vehicle is invalid",
+			id:          "1",
+			contentType: "application/merge-patch+json",
+			patch:       `{"battery": 130}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"code":1003,"message":"The request payload is invalid","details":["battery level must be > 0 and <= 100"]}`,
+		},
+		{
+			desc:        "wrongly typed field",
+			id:          "1",
+			contentType: "application/merge-patch+json",
+			patch:       `{"battery": "full"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"code":1003,"message":"The request payload is invalid","details":["invalid patch document"]}`,
+		},
+		{
+			desc:        "unknown vehicle",
+			id:          "3",
+			contentType: "application/merge-patch+json",
+			patch:       `{"battery": 12}`,
+			wantStatus:  http.StatusNotFound,
+			wantBody:    `{"code":1004,"message":"The vehicle does not exist","details":{"id":3}}`,
+		},
+		{
+			desc:        "shortcode of another vehicle",
+			id:          "1",
+			contentType: "application/merge-patch+json",
+			patch:       `{"shortcode": "efgh"}`,
+			wantStatus:  http.StatusConflict,
+			wantBody:    `{"code":1005,"message":"The shortcode is already used by another vehicle","details":{"shortcode":"efgh"}}`,
+		},
+		{
+			desc:        "unexpected content type",
+			id:          "1",
+			contentType: "application/json",
+			patch:       `{"battery": 12}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"code":3,"message":"Unexpected request content type","details":{"expected":"application/merge-patch+json","got":"application/json"}}`,
+		},
+	} {
+		t.Run(testCase.desc, func(t *testing.T) {
+			store := newMemoryStore()
+
+			for _, shortCode := range []string{"abcd", "efgh"} {
+				_, err := store.Vehicle().Create(
+					context.Background(),
+					vehiclestore.Vehicle{
+						ShortCode:    shortCode,
+						BatteryLevel: 42,
+						Position:     vehiclestore.Point{Latitude: 12.5, Longitude: 3.2},
+					},
+				)
+				require.NoError(t, err)
+			}
+
+			handler := vehicle.NewUpdateHandler(store, zap.NewNop())
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(
+				http.MethodPatch,
+				"/vehicles/"+testCase.id,
+				strings.NewReader(testCase.patch),
+			)
+			req.Header.Add("Content-Type", testCase.contentType)
+			req.SetPathValue("id", testCase.id)
+
+			handler.ServeHTTP(resp, req)
+
+			assert.Equal(t, testCase.wantStatus, resp.Result().StatusCode)
+			assert.JSONEq(t, testCase.wantBody, resp.Body.String())
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package vehicle
 
-import "github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+import (
+	"time"
+
+	"github.com/Cirederf1/vehicle-server/storage/vehiclestore"
+)
 
 type Vehicle struct {
 	Latitude     float64 `json:"latitude"`
@@ -8,6 +12,14 @@ type Vehicle struct {
 	ShortCode    string  `json:"shortcode"`
 	BatteryLevel int64   `json:"battery"`
 	ID           int64   `json:"id"`
+	Status       string  `json:"status"`
+	Type         string  `json:"type"`
+	// HelmetBox and Seats depend on the type, they are omitted when not relevant.
+	HelmetBox *bool  `json:"helmet_box,omitempty"`
+	Seats     *int64 `json:"seats,omitempty"`
+	// CreatedAt and UpdatedAt are encoded as RFC 3339 timestamps.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 func newVehicleFromModel(v vehiclestore.Vehicle) Vehicle {
@@ -17,5 +29,11 @@ func newVehicleFromModel(v vehiclestore.Vehicle) Vehicle {
 		Latitude:     v.Position.Latitude,
 		Longitude:    v.Position.Longitude,
 		BatteryLevel: v.BatteryLevel,
+		Status:       string(v.Status),
+		Type:         string(v.Type),
+		HelmetBox:    v.Attributes.HelmetBox,
+		Seats:        v.Attributes.Seats,
+		CreatedAt:    v.CreatedAt,
+		UpdatedAt:    v.UpdatedAt,
 	}
 }